@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrJobNotFound   = errors.New("job does not exist")
+	ErrVideoNotFound = errors.New("video does not exist")
+	ErrNoPendingJob  = errors.New("no pending job")
+
+	// ErrConcurrentModification is returned by Update when the job was
+	// updated by someone else since it was read.
+	ErrConcurrentModification = errors.New("job was modified concurrently")
+)
+
+// DatabaseError wraps an error returned by the database driver, e.g. a lost
+// connection or a constraint violation.
+type DatabaseError struct {
+	Op  string
+	Err error
+}
+
+func (e *DatabaseError) Error() string {
+	return fmt.Sprintf("error trying to %s: %v", e.Op, e.Err)
+}
+
+func (e *DatabaseError) Unwrap() error {
+	return e.Err
+}
+
+func wrapError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &DatabaseError{Op: op, Err: err}
+}
@@ -2,15 +2,22 @@ package repository
 
 import (
 	"encoder/domain"
-	"fmt"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type JobRepository interface {
 	Insert(job *domain.Job) (*domain.Job, error)
 	Find(id string) (*domain.Job, error)
 	Update(job *domain.Job) (*domain.Job, error)
+	List(filter JobFilter) (*Page[*domain.Job], error)
+	CountByStatus(filter JobFilter) (map[string]int64, error)
+	Heartbeat(id string) error
+	Claim(workerID string, maxPerTenant int) (*domain.Job, error)
+	HardDelete(id string) error
 }
 
 type JobRepositoryDb struct {
@@ -24,7 +31,7 @@ func NewJobRepository(db *gorm.DB) *JobRepositoryDb {
 func (repo JobRepositoryDb) Insert(job *domain.Job) (*domain.Job, error) {
 	err := repo.Db.Create(job).Error
 	if err != nil {
-		return nil, err
+		return nil, wrapError("insert job", err)
 	}
 
 	return job, nil
@@ -32,20 +39,228 @@ func (repo JobRepositoryDb) Insert(job *domain.Job) (*domain.Job, error) {
 
 func (repo JobRepositoryDb) Find(id string) (*domain.Job, error) {
 	var job domain.Job
-	repo.Db.Preload("Video").First(&job, "id = ?", id)
+	err := repo.Db.Preload("Video.Renditions").First(&job, "id = ?", id).Error
 
-	if job.ID == "" {
-		return nil, fmt.Errorf("job does not exist")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, wrapError("find job", err)
 	}
 
 	return &job, nil
 }
 
+// Update saves the job only if its version is still the one it was read
+// with, and bumps the version. The heartbeat is left alone, it is only
+// written by Heartbeat.
 func (repo JobRepositoryDb) Update(job *domain.Job) (*domain.Job, error) {
-	err := repo.Db.Save(job).Error
+	expected := job.Version
+	job.Version++
+
+	result := repo.Db.Model(job).
+		Select("*").
+		Omit(clause.Associations, "HeartbeatAt").
+		Where("version = ?", expected).
+		Updates(job)
+	if result.Error != nil {
+		job.Version = expected
+		return nil, wrapError("update job", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		job.Version = expected
+		return nil, ErrConcurrentModification
+	}
+
+	return job, nil
+}
+
+// Heartbeat records that the worker owning the job is still alive. It does
+// not bump the version, so it never conflicts with status updates.
+func (repo JobRepositoryDb) Heartbeat(id string) error {
+	err := repo.Db.Model(&domain.Job{}).Where("id = ?", id).UpdateColumn("heartbeat_at", time.Now()).Error
+
+	return wrapError("update job heartbeat", err)
+}
+
+// Claim hands the PENDING job with the highest priority, oldest first, to a
+// worker and moves it to STARTING. Jobs of tenants already running
+// maxPerTenant jobs are skipped when maxPerTenant is positive.
+// On postgres concurrent workers skip the rows locked by each other; on other
+// databases the conditional update makes sure only one of them wins, the
+// others get ErrConcurrentModification. On postgres the tenant cap is checked
+// again under a per-tenant advisory lock, so concurrent workers can't start
+// more than maxPerTenant jobs of a tenant. Chunk jobs are never claimed, they
+// are run by their parent.
+func (repo JobRepositoryDb) Claim(workerID string, maxPerTenant int) (*domain.Job, error) {
+	var job domain.Job
+
+	err := repo.Db.Transaction(func(tx *gorm.DB) error {
+		query := tx.
+			Where("status = ? AND parent_job_id IS NULL", "PENDING").
+			Order("priority DESC").
+			Order("created_at").
+			Limit(1)
+		if maxPerTenant > 0 {
+			busyTenants := runningJobs(tx).
+				Select("tenant").
+				Where("tenant <> ''").
+				Group("tenant").
+				Having("COUNT(*) >= ?", maxPerTenant)
+			query = query.Where("tenant NOT IN (?)", busyTenants)
+		}
+		if tx.Dialector.Name() == "postgres" {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+
+		err := query.Take(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoPendingJob
+		}
+		if err != nil {
+			return wrapError("claim job", err)
+		}
+
+		if maxPerTenant > 0 && job.Tenant != "" && tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "jobs.tenant:"+job.Tenant).Error; err != nil {
+				return wrapError("claim job", err)
+			}
+
+			var running int64
+			if err := runningJobs(tx).Where("tenant = ?", job.Tenant).Count(&running).Error; err != nil {
+				return wrapError("claim job", err)
+			}
+			if running >= int64(maxPerTenant) {
+				return ErrConcurrentModification
+			}
+		}
+
+		result := tx.Model(&domain.Job{}).
+			Where("id = ? AND status = ?", job.ID, "PENDING").
+			Updates(map[string]any{
+				"status":       "STARTING",
+				"worker_id":    workerID,
+				"heartbeat_at": time.Now(),
+				"version":      gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return wrapError("claim job", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrConcurrentModification
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return job, nil
+	return repo.Find(job.ID)
+}
+
+// runningJobs selects the top-level jobs claimed or started and not finished.
+func runningJobs(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&domain.Job{}).
+		Where("status NOT IN ? AND status <> ?", TerminalJobStatuses, "PENDING").
+		Where("parent_job_id IS NULL")
+}
+
+// List returns the jobs matching the filter, newest first.
+func (repo JobRepositoryDb) List(filter JobFilter) (*Page[*domain.Job], error) {
+	query, err := paginate(repo.filterJobs(filter).Preload("Video"), "jobs", filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*domain.Job
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, wrapError("list jobs", err)
+	}
+
+	page := &Page[*domain.Job]{Items: jobs}
+	if size := pageSize(filter.Limit); len(jobs) > size {
+		page.Items = jobs[:size]
+		last := page.Items[size-1]
+		page.NextCursor = pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	}
+
+	return page, nil
+}
+
+// CountByStatus counts the jobs matching the filter per status. Cursor and
+// limit are ignored.
+func (repo JobRepositoryDb) CountByStatus(filter JobFilter) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+
+	err := repo.filterJobs(filter).
+		Select("jobs.status AS status, COUNT(*) AS count").
+		Group("jobs.status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, wrapError("count jobs", err)
+	}
+
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
+
+// HardDelete removes the job row, soft-deleted or not.
+func (repo JobRepositoryDb) HardDelete(id string) error {
+	err := repo.Db.Unscoped().Delete(&domain.Job{}, "id = ?", id).Error
+
+	return wrapError("delete job", err)
+}
+
+func (repo JobRepositoryDb) filterJobs(filter JobFilter) *gorm.DB {
+	query := repo.Db.Model(&domain.Job{})
+
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if !filter.DeletedBefore.IsZero() {
+		query = query.Unscoped().Where("jobs.deleted_at < ?", filter.DeletedBefore)
+	}
+	if filter.VideoId != "" {
+		query = query.Where("jobs.video_id = ?", filter.VideoId)
+	}
+	if filter.ParentJobId != "" {
+		query = query.Where("jobs.parent_job_id = ?", filter.ParentJobId)
+	}
+	if filter.TopLevel {
+		query = query.Where("jobs.parent_job_id IS NULL")
+	}
+
+	if len(filter.Statuses) > 0 {
+		query = query.Where("jobs.status IN ?", filter.Statuses)
+	}
+	if filter.ResourceId != "" {
+		query = query.
+			Joins("JOIN videos ON videos.id = jobs.video_id").
+			Where("videos.resource_id = ?", filter.ResourceId)
+	}
+	if filter.StuckFor > 0 {
+		query = query.
+			Where("jobs.status NOT IN ?", TerminalJobStatuses).
+			Where("jobs.updated_at < ?", time.Now().Add(-filter.StuckFor))
+	}
+
+	if !filter.HeartbeatBefore.IsZero() {
+		query = query.
+			Where("jobs.status NOT IN ?", TerminalJobStatuses).
+			Where("jobs.heartbeat_at < ?", filter.HeartbeatBefore)
+	}
+
+	query = timeRange(query, "jobs.created_at", filter.CreatedAfter, filter.CreatedBefore)
+	query = timeRange(query, "jobs.updated_at", filter.UpdatedAfter, filter.UpdatedBefore)
+
+	return query
 }
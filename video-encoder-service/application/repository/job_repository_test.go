@@ -5,6 +5,7 @@ import (
 	"encoder/domain"
 	"encoder/framework/database"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -72,3 +73,248 @@ func TestJobRepository_Update(t *testing.T) {
 	assert.Equal(t, job.Video.ID, j.Video.ID)
 	assert.Equal(t, "Complete", j.Status)
 }
+
+func TestJobRepository_PersistsOptions(t *testing.T) {
+	db := database.NewDbTest()
+
+	video := createTestVideo(db)
+	job, err := domain.NewJob("path", "pending", video)
+	assert.Nil(t, err)
+	job.Options.Sprites = &domain.SpriteOptions{Interval: 5}
+
+	jobRepo := repository.NewJobRepository(db)
+	_, err = jobRepo.Insert(job)
+	assert.Nil(t, err)
+
+	j, err := jobRepo.Find(job.ID)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, j.Options.Sprites)
+	assert.Equal(t, 5, j.Options.Sprites.Interval)
+}
+
+func TestJobRepository_UpdateConcurrentModification(t *testing.T) {
+	db := database.NewDbTest()
+
+	video := createTestVideo(db)
+	job, err := createTestJob(db, video)
+	assert.Nil(t, err)
+
+	jobRepo := repository.NewJobRepository(db)
+	stale, err := jobRepo.Find(job.ID)
+	assert.Nil(t, err)
+
+	job.Status = "DOWNLOADING"
+	_, err = jobRepo.Update(job)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, job.Version)
+
+	stale.Status = "FAILED"
+	_, err = jobRepo.Update(stale)
+	assert.ErrorIs(t, err, repository.ErrConcurrentModification)
+	assert.Equal(t, 1, stale.Version)
+
+	j, err := jobRepo.Find(job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "DOWNLOADING", j.Status)
+}
+
+func TestJobRepository_FindNotFound(t *testing.T) {
+	db := database.NewDbTest()
+
+	jobRepo := repository.NewJobRepository(db)
+	_, err := jobRepo.Find(uuid.New().String())
+
+	assert.ErrorIs(t, err, repository.ErrJobNotFound)
+}
+
+func TestJobRepository_FindDatabaseError(t *testing.T) {
+	db := database.NewDbTest()
+	sqlDb, err := db.DB()
+	assert.Nil(t, err)
+	sqlDb.Close()
+
+	jobRepo := repository.NewJobRepository(db)
+	_, err = jobRepo.Find(uuid.New().String())
+
+	var databaseError *repository.DatabaseError
+	assert.ErrorAs(t, err, &databaseError)
+	assert.NotErrorIs(t, err, repository.ErrJobNotFound)
+}
+
+func TestJobRepository_List(t *testing.T) {
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+
+	video := createTestVideo(db)
+	for i := 0; i < 5; i++ {
+		job, err := domain.NewJob("path", "COMPLETED", video)
+		assert.Nil(t, err)
+		job.CreatedAt = job.CreatedAt.Add(time.Duration(i) * time.Second)
+		_, err = jobRepo.Insert(job)
+		assert.Nil(t, err)
+	}
+	_, err := createTestJob(db, createTestVideo(db))
+	assert.Nil(t, err)
+
+	page, err := jobRepo.List(repository.JobFilter{Statuses: []string{"COMPLETED"}, Limit: 3})
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 3)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.True(t, page.Items[0].CreatedAt.After(page.Items[1].CreatedAt))
+
+	next, err := jobRepo.List(repository.JobFilter{Statuses: []string{"COMPLETED"}, Limit: 3, Cursor: page.NextCursor})
+	assert.Nil(t, err)
+	assert.Len(t, next.Items, 2)
+	assert.Empty(t, next.NextCursor)
+	assert.True(t, page.Items[2].CreatedAt.After(next.Items[0].CreatedAt))
+
+	_, err = jobRepo.List(repository.JobFilter{Cursor: "not a cursor"})
+	assert.NotNil(t, err)
+}
+
+func TestJobRepository_ListByResource(t *testing.T) {
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+
+	video := domain.NewVideo()
+	video.ResourceId = uuid.New().String()
+	video.FilePath = "path"
+	_, err := repository.NewVideoRepository(db).Insert(video)
+	assert.Nil(t, err)
+
+	job, err := createTestJob(db, video)
+	assert.Nil(t, err)
+	_, err = createTestJob(db, createTestVideo(db))
+	assert.Nil(t, err)
+
+	page, err := jobRepo.List(repository.JobFilter{ResourceId: video.ResourceId})
+
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, job.ID, page.Items[0].ID)
+	assert.Equal(t, video.ID, page.Items[0].Video.ID)
+}
+
+func TestJobRepository_ListStuck(t *testing.T) {
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+
+	video := createTestVideo(db)
+	stuck, err := createTestJob(db, video)
+	assert.Nil(t, err)
+	_, err = createTestJob(db, video)
+	assert.Nil(t, err)
+
+	db.Model(&domain.Job{}).Where("id = ?", stuck.ID).UpdateColumn("updated_at", time.Now().Add(-time.Hour))
+
+	page, err := jobRepo.List(repository.JobFilter{StuckFor: 30 * time.Minute})
+
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, stuck.ID, page.Items[0].ID)
+}
+
+func TestJobRepository_CountByStatus(t *testing.T) {
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+
+	video := createTestVideo(db)
+	for _, status := range []string{"COMPLETED", "COMPLETED", "FAILED"} {
+		job, err := domain.NewJob("path", status, video)
+		assert.Nil(t, err)
+		_, err = jobRepo.Insert(job)
+		assert.Nil(t, err)
+	}
+
+	counts, err := jobRepo.CountByStatus(repository.JobFilter{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"COMPLETED": 2, "FAILED": 1}, counts)
+}
+
+func TestJobRepository_Heartbeat(t *testing.T) {
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+
+	video := createTestVideo(db)
+	job, err := domain.NewJob("path", "ENCODING", video)
+	assert.Nil(t, err)
+	stale := time.Now().Add(-time.Hour)
+	job.HeartbeatAt = &stale
+	_, err = jobRepo.Insert(job)
+	assert.Nil(t, err)
+
+	page, err := jobRepo.List(repository.JobFilter{HeartbeatBefore: time.Now().Add(-time.Minute)})
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 1)
+
+	assert.Nil(t, jobRepo.Heartbeat(job.ID))
+
+	job.Status = "UPLOADING"
+	_, err = jobRepo.Update(job)
+	assert.Nil(t, err)
+
+	page, err = jobRepo.List(repository.JobFilter{HeartbeatBefore: time.Now().Add(-time.Minute)})
+	assert.Nil(t, err)
+	assert.Empty(t, page.Items)
+}
+
+func TestJobRepository_Claim(t *testing.T) {
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+
+	video := createTestVideo(db)
+	job, err := domain.NewJob("path", "PENDING", video)
+	assert.Nil(t, err)
+	_, err = jobRepo.Insert(job)
+	assert.Nil(t, err)
+
+	chunk, err := domain.NewChunkJob(job, 0)
+	assert.Nil(t, err)
+	_, err = jobRepo.Insert(chunk)
+	assert.Nil(t, err)
+
+	claimed, err := jobRepo.Claim("worker-1", 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+	assert.Equal(t, "STARTING", claimed.Status)
+	assert.Equal(t, "worker-1", claimed.WorkerID)
+	assert.Equal(t, 2, claimed.Version)
+	assert.NotNil(t, claimed.HeartbeatAt)
+	assert.Equal(t, video.ID, claimed.Video.ID)
+
+	_, err = jobRepo.Claim("worker-2", 0)
+	assert.ErrorIs(t, err, repository.ErrNoPendingJob)
+}
+
+func TestJobRepository_ClaimByPriorityAndTenant(t *testing.T) {
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+
+	video := createTestVideo(db)
+	insert := func(status string, tenant string, priority int) *domain.Job {
+		job, err := domain.NewJob("path", status, video)
+		assert.Nil(t, err)
+		job.Tenant = tenant
+		job.Priority = priority
+		_, err = jobRepo.Insert(job)
+		assert.Nil(t, err)
+		return job
+	}
+
+	insert("ENCODING", "bulk", 0)
+	insert("PENDING", "bulk", 9)
+	low := insert("PENDING", "other", 0)
+	high := insert("PENDING", "other", 5)
+
+	claimed, err := jobRepo.Claim("worker-1", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, high.ID, claimed.ID)
+
+	claimed, err = jobRepo.Claim("worker-1", 0)
+	assert.Nil(t, err)
+	assert.NotEqual(t, low.ID, claimed.ID)
+	assert.Equal(t, "bulk", claimed.Tenant)
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"encoder/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	Insert(notification *domain.Notification) (*domain.Notification, error)
+	ListUnpublished(limit int) ([]*domain.Notification, error)
+	MarkPublished(id string) error
+}
+
+type NotificationRepositoryDb struct {
+	Db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepositoryDb {
+	return &NotificationRepositoryDb{Db: db}
+}
+
+func (repo NotificationRepositoryDb) Insert(notification *domain.Notification) (*domain.Notification, error) {
+	err := repo.Db.Create(notification).Error
+	if err != nil {
+		return nil, wrapError("insert notification", err)
+	}
+
+	return notification, nil
+}
+
+// ListUnpublished returns the oldest notifications not delivered yet.
+func (repo NotificationRepositoryDb) ListUnpublished(limit int) ([]*domain.Notification, error) {
+	var notifications []*domain.Notification
+
+	err := repo.Db.
+		Where("published_at IS NULL").
+		Order("created_at").
+		Limit(pageSize(limit)).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, wrapError("list notifications", err)
+	}
+
+	return notifications, nil
+}
+
+func (repo NotificationRepositoryDb) MarkPublished(id string) error {
+	err := repo.Db.Model(&domain.Notification{}).Where("id = ?", id).Update("published_at", time.Now()).Error
+
+	return wrapError("mark notification published", err)
+}
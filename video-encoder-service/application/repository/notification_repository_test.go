@@ -0,0 +1,31 @@
+package repository_test
+
+import (
+	"encoder/application/repository"
+	"encoder/domain"
+	"encoder/framework/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationRepository(t *testing.T) {
+	db := database.NewDbTest()
+	repo := repository.NewNotificationRepository(db)
+
+	notification := domain.NewNotification(`{"status":"COMPLETED"}`, "application/json", "amq.direct", "jobs")
+	_, err := repo.Insert(notification)
+	assert.Nil(t, err)
+
+	unpublished, err := repo.ListUnpublished(10)
+	assert.Nil(t, err)
+	assert.Len(t, unpublished, 1)
+	assert.Equal(t, notification.Body, unpublished[0].Body)
+	assert.Equal(t, "jobs", unpublished[0].RoutingKey)
+
+	assert.Nil(t, repo.MarkPublished(notification.ID))
+
+	unpublished, err = repo.ListUnpublished(10)
+	assert.Nil(t, err)
+	assert.Empty(t, unpublished)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// TerminalJobStatuses are the statuses a job never leaves, so a job in one of
+// them is never considered stuck.
+var TerminalJobStatuses = []string{"COMPLETED", "FAILED"}
+
+// JobFilter narrows a job listing. Zero values are ignored.
+type JobFilter struct {
+	Statuses      []string
+	ResourceId    string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	// StuckFor selects jobs that are not finished and whose status has not
+	// changed for at least this long.
+	StuckFor time.Duration
+	// HeartbeatBefore selects jobs that are not finished and whose worker
+	// last reported before this instant.
+	HeartbeatBefore time.Time
+	VideoId         string
+	// ParentJobId selects the chunk jobs of a job, TopLevel only the jobs
+	// that are not chunks of another.
+	ParentJobId string
+	TopLevel    bool
+	// IncludeDeleted also lists soft-deleted jobs, DeletedBefore lists only
+	// the jobs soft-deleted before this instant.
+	IncludeDeleted bool
+	DeletedBefore  time.Time
+	Cursor         string
+	Limit          int
+}
+
+type VideoFilter struct {
+	ResourceId    string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Cursor        string
+	Limit         int
+}
+
+// Page is one page of a listing, newest first. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// pageCursor points at the last item of a page, ordered by creation time and
+// then id so items created at the same instant are not skipped.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func (c pageCursor) encode() string {
+	raw := c.CreatedAt.Format(time.RFC3339Nano) + "|" + c.ID
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	return &pageCursor{CreatedAt: t, ID: id}, nil
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+
+	return min(limit, MaxPageSize)
+}
+
+// paginate orders the query newest first and starts it after the cursor. It
+// asks for one extra row to know whether there is a next page.
+func paginate(query *gorm.DB, table string, cursor string, limit int) (*gorm.DB, error) {
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		query = query.Where(
+			fmt.Sprintf("%[1]s.created_at < ? OR (%[1]s.created_at = ? AND %[1]s.id < ?)", table),
+			after.CreatedAt, after.CreatedAt, after.ID,
+		)
+	}
+
+	return query.
+		Order(fmt.Sprintf("%s.created_at DESC", table)).
+		Order(fmt.Sprintf("%s.id DESC", table)).
+		Limit(pageSize(limit) + 1), nil
+}
+
+func timeRange(query *gorm.DB, column string, after time.Time, before time.Time) *gorm.DB {
+	if !after.IsZero() {
+		query = query.Where(column+" >= ?", after)
+	}
+	if !before.IsZero() {
+		query = query.Where(column+" < ?", before)
+	}
+
+	return query
+}
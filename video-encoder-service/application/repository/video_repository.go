@@ -2,7 +2,7 @@ package repository
 
 import (
 	"encoder/domain"
-	"fmt"
+	"errors"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -11,6 +11,11 @@ import (
 type VideoRepository interface {
 	Insert(video *domain.Video) (*domain.Video, error)
 	Find(id string) (*domain.Video, error)
+	Update(video *domain.Video) (*domain.Video, error)
+	List(filter VideoFilter) (*Page[*domain.Video], error)
+	Delete(id string) error
+	HardDelete(id string) error
+	ReplaceRenditions(video *domain.Video) error
 }
 
 type VideoRepositoryDb struct {
@@ -28,7 +33,7 @@ func (repo VideoRepositoryDb) Insert(video *domain.Video) (*domain.Video, error)
 
 	err := repo.Db.Create(video).Error
 	if err != nil {
-		return nil, err
+		return nil, wrapError("insert video", err)
 	}
 
 	return video, nil
@@ -36,11 +41,104 @@ func (repo VideoRepositoryDb) Insert(video *domain.Video) (*domain.Video, error)
 
 func (repo VideoRepositoryDb) Find(id string) (*domain.Video, error) {
 	var video domain.Video
-	repo.Db.Preload("Jobs").First(&video, "id = ?", id)
+	err := repo.Db.Preload("Jobs").Preload("Renditions").First(&video, "id = ?", id).Error
 
-	if video.ID == "" {
-		return nil, fmt.Errorf("video does not exist")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrVideoNotFound
+	}
+	if err != nil {
+		return nil, wrapError("find video", err)
 	}
 
 	return &video, nil
 }
+
+func (repo VideoRepositoryDb) Update(video *domain.Video) (*domain.Video, error) {
+	err := repo.Db.Omit("Jobs", "Renditions").Save(video).Error
+	if err != nil {
+		return nil, wrapError("update video", err)
+	}
+
+	return video, nil
+}
+
+// List returns the videos matching the filter, newest first.
+func (repo VideoRepositoryDb) List(filter VideoFilter) (*Page[*domain.Video], error) {
+	query := repo.Db.Model(&domain.Video{})
+	if filter.ResourceId != "" {
+		query = query.Where("videos.resource_id = ?", filter.ResourceId)
+	}
+	query = timeRange(query, "videos.created_at", filter.CreatedAfter, filter.CreatedBefore)
+
+	query, err := paginate(query, "videos", filter.Cursor, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var videos []*domain.Video
+	if err := query.Find(&videos).Error; err != nil {
+		return nil, wrapError("list videos", err)
+	}
+
+	page := &Page[*domain.Video]{Items: videos}
+	if size := pageSize(filter.Limit); len(videos) > size {
+		page.Items = videos[:size]
+		last := page.Items[size-1]
+		page.NextCursor = pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	}
+
+	return page, nil
+}
+
+// Delete soft-deletes the video and its jobs.
+func (repo VideoRepositoryDb) Delete(id string) error {
+	err := repo.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("video_id = ?", id).Delete(&domain.Job{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&domain.Video{}, "id = ?", id).Error
+	})
+
+	return wrapError("delete video", err)
+}
+
+// HardDelete removes the video row with its renditions and, through the
+// foreign key, its jobs.
+func (repo VideoRepositoryDb) HardDelete(id string) error {
+	err := repo.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("video_id = ?", id).Delete(&domain.Rendition{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&domain.Video{}, "id = ?", id).Error
+	})
+
+	return wrapError("delete video", err)
+}
+
+// ReplaceRenditions saves the manifests and renditions of the video,
+// replacing whatever a previous encode recorded.
+func (repo VideoRepositoryDb) ReplaceRenditions(video *domain.Video) error {
+	err := repo.Db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(video).Updates(map[string]any{
+			"dash_manifest": video.DashManifest,
+			"hls_manifest":  video.HlsManifest,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("video_id = ?", video.ID).Delete(&domain.Rendition{}).Error; err != nil {
+			return err
+		}
+
+		if len(video.Renditions) == 0 {
+			return nil
+		}
+
+		return tx.Create(video.Renditions).Error
+	})
+
+	return wrapError("save renditions", err)
+}
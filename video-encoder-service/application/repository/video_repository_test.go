@@ -26,3 +26,127 @@ func TestVideoRepository_Insert(t *testing.T) {
 	assert.NotNil(t, v.ID)
 	assert.Equal(t, video.ID, v.ID)
 }
+
+func TestVideoRepository_Update(t *testing.T) {
+	db := database.NewDbTest()
+
+	video := domain.NewVideo()
+	video.ID = uuid.New().String()
+	video.FilePath = "path"
+
+	repo := repository.NewVideoRepository(db)
+	repo.Insert(video)
+
+	video.MediaInfo.VideoCodec = "h264"
+	video.MediaInfo.AudioLanguages = []string{"en", "pt"}
+	_, err := repo.Update(video)
+	assert.Nil(t, err)
+
+	v, err := repo.Find(video.ID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "h264", v.MediaInfo.VideoCodec)
+	assert.Equal(t, []string{"en", "pt"}, v.MediaInfo.AudioLanguages)
+}
+
+func TestVideoRepository_ReplaceRenditions(t *testing.T) {
+	db := database.NewDbTest()
+
+	video := domain.NewVideo()
+	video.ID = uuid.New().String()
+	video.FilePath = "path"
+
+	repo := repository.NewVideoRepository(db)
+	repo.Insert(video)
+
+	video.DashManifest = video.ID + "/stream.mpd"
+	video.Renditions = []*domain.Rendition{domain.NewRendition(video.ID), domain.NewRendition(video.ID)}
+	assert.Nil(t, repo.ReplaceRenditions(video))
+
+	rendition := domain.NewRendition(video.ID)
+	rendition.Codec = "avc1.640028"
+	rendition.Height = 720
+	video.Renditions = []*domain.Rendition{rendition}
+	assert.Nil(t, repo.ReplaceRenditions(video))
+
+	v, err := repo.Find(video.ID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, video.ID+"/stream.mpd", v.DashManifest)
+	assert.Len(t, v.Renditions, 1)
+	assert.Equal(t, "avc1.640028", v.Renditions[0].Codec)
+	assert.Equal(t, 720, v.Renditions[0].Height)
+}
+
+func TestVideoRepository_FindNotFound(t *testing.T) {
+	db := database.NewDbTest()
+
+	repo := repository.NewVideoRepository(db)
+	_, err := repo.Find(uuid.New().String())
+
+	assert.ErrorIs(t, err, repository.ErrVideoNotFound)
+}
+
+func TestVideoRepository_List(t *testing.T) {
+	db := database.NewDbTest()
+	repo := repository.NewVideoRepository(db)
+
+	resourceId := uuid.New().String()
+	for i := 0; i < 3; i++ {
+		video := domain.NewVideo()
+		video.ResourceId = resourceId
+		video.FilePath = "path"
+		_, err := repo.Insert(video)
+		assert.Nil(t, err)
+	}
+	other := domain.NewVideo()
+	other.ResourceId = uuid.New().String()
+	other.FilePath = "path"
+	_, err := repo.Insert(other)
+	assert.Nil(t, err)
+
+	page, err := repo.List(repository.VideoFilter{ResourceId: resourceId, Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 2)
+	assert.NotEmpty(t, page.NextCursor)
+
+	next, err := repo.List(repository.VideoFilter{ResourceId: resourceId, Limit: 2, Cursor: page.NextCursor})
+	assert.Nil(t, err)
+	assert.Len(t, next.Items, 1)
+	assert.Empty(t, next.NextCursor)
+}
+
+func TestVideoRepository_Delete(t *testing.T) {
+	db := database.NewDbTest()
+	repo := repository.NewVideoRepository(db)
+	jobRepo := repository.NewJobRepository(db)
+
+	video := domain.NewVideo()
+	video.ResourceId = uuid.New().String()
+	video.FilePath = "path"
+	_, err := repo.Insert(video)
+	assert.Nil(t, err)
+
+	job, err := domain.NewJob("path", "COMPLETED", video)
+	assert.Nil(t, err)
+	_, err = jobRepo.Insert(job)
+	assert.Nil(t, err)
+
+	assert.Nil(t, repo.Delete(video.ID))
+
+	_, err = repo.Find(video.ID)
+	assert.ErrorIs(t, err, repository.ErrVideoNotFound)
+	_, err = jobRepo.Find(job.ID)
+	assert.ErrorIs(t, err, repository.ErrJobNotFound)
+
+	page, err := jobRepo.List(repository.JobFilter{VideoId: video.ID, IncludeDeleted: true})
+	assert.Nil(t, err)
+	assert.Len(t, page.Items, 1)
+
+	assert.Nil(t, jobRepo.HardDelete(job.ID))
+	assert.Nil(t, repo.HardDelete(video.ID))
+
+	page, err = jobRepo.List(repository.JobFilter{VideoId: video.ID, IncludeDeleted: true})
+	assert.Nil(t, err)
+	assert.Empty(t, page.Items)
+}
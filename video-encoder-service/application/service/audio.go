@@ -0,0 +1,122 @@
+package service
+
+import (
+	"encoder/domain"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+var audioRoles = map[string]bool{
+	"main":        true,
+	"dub":         true,
+	"alternate":   true,
+	"commentary":  true,
+	"description": true,
+}
+
+// PrepareAudioTracks checks the requested source languages against the probe
+// result, then downloads every extra audio file, converts it to AAC and
+// fragments it so mp4dash can package it as its own adaptation set.
+func (v *VideoService) PrepareAudioTracks(bucketName string) error {
+	if err := ValidateAudioLanguages(v.Options.AudioLanguages, v.Video.MediaInfo); err != nil {
+		return err
+	}
+	if err := ValidateAudioTracks(v.Options.AudioTracks); err != nil {
+		return err
+	}
+
+	if len(v.Options.AudioTracks) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(v.audioTracksPath(), os.ModePerm); err != nil {
+		return err
+	}
+
+	for i, audio := range v.Options.AudioTracks {
+		download := fmt.Sprintf("%s/%d_%s%s", v.audioTracksPath(), i, audio.Language, sourceExt(audio.FilePath))
+		converted := fmt.Sprintf("%s/%d_%s.m4a", v.audioTracksPath(), i, audio.Language)
+
+		if err := downloadObject(bucketName, audio.FilePath, download); err != nil {
+			return fmt.Errorf("error downloading audio track %v: %w", audio.FilePath, err)
+		}
+
+		err := runFFmpeg("-y", "-i", download, "-vn", "-map", "0:a:0", "-c:a", "aac", "-b:a", "160k", converted)
+		if err != nil {
+			return NewJobError(ErrCodeInvalidSource, fmt.Errorf("error converting audio track %v: %w", audio.FilePath, err))
+		}
+
+		cmd := exec.Command("mp4fragment", converted, v.audioPath(i, audio))
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			printOutput(output)
+			return err
+		}
+	}
+
+	log.Printf("%d audio tracks prepared for video %v", len(v.Options.AudioTracks), v.Video.ID)
+
+	return nil
+}
+
+// ValidateAudioLanguages checks the requested source languages against the
+// probe result.
+func ValidateAudioLanguages(languages []string, info domain.MediaInfo) error {
+	for _, language := range languages {
+		if !slices.Contains(info.AudioLanguages, language) {
+			return NewJobError(
+				ErrCodeInvalidRequest,
+				fmt.Errorf("source has no %q audio track (available: %s)", language, strings.Join(info.AudioLanguages, ", ")),
+			)
+		}
+	}
+
+	return nil
+}
+
+// ValidateAudioTracks checks the extra audio tracks of an encode message,
+// before anything is downloaded.
+func ValidateAudioTracks(tracks []domain.AudioTrack) error {
+	for _, audio := range tracks {
+		if audio.FilePath == "" {
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("audio track file_path can't be empty"))
+		}
+		if !languageCode.MatchString(audio.Language) {
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("invalid audio track language %q", audio.Language))
+		}
+		if audio.Role != "" && !audioRoles[audio.Role] {
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("invalid audio track role %q", audio.Role))
+		}
+	}
+
+	return nil
+}
+
+// AudioInput builds the mp4dash input for an extra audio track, carrying its
+// language, label and role into the manifest.
+func AudioInput(audio domain.AudioTrack, path string) string {
+	spec := []string{"type=audio", "+language=" + audio.Language}
+	if audio.Label != "" {
+		spec = append(spec, "+language_name="+audio.Label)
+	}
+
+	role := audio.Role
+	if role == "" {
+		role = "dub"
+	}
+	spec = append(spec, "+role="+role)
+
+	return fmt.Sprintf("[%s]%s", strings.Join(spec, ","), path)
+}
+
+func (v *VideoService) audioTracksPath() string {
+	return fmt.Sprintf("%s/%s.audio", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+}
+
+func (v *VideoService) audioPath(index int, audio domain.AudioTrack) string {
+	return fmt.Sprintf("%s/%d_%s.frag", v.audioTracksPath(), index, audio.Language)
+}
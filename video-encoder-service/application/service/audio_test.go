@@ -0,0 +1,52 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudioInput(t *testing.T) {
+	audio := domain.AudioTrack{FilePath: "dub/es.wav", Language: "es", Label: "Español"}
+
+	assert.Equal(t, "[type=audio,+language=es,+language_name=Español,+role=dub]/tmp/0_es.frag", service.AudioInput(audio, "/tmp/0_es.frag"))
+}
+
+func TestValidateAudioTracks(t *testing.T) {
+	info := domain.MediaInfo{AudioLanguages: []string{"eng", "por"}}
+
+	assert.Nil(t, service.ValidateAudioLanguages([]string{"por"}, info))
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateAudioLanguages([]string{"fra"}, info)))
+
+	assert.Nil(t, service.ValidateAudioTracks([]domain.AudioTrack{{FilePath: "dub/es.wav", Language: "es", Role: "commentary"}}))
+
+	invalidRole := []domain.AudioTrack{{FilePath: "dub/es.wav", Language: "es", Role: "karaoke"}}
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateAudioTracks(invalidRole)))
+}
+
+func TestVideoService_EncodeArgsWithExtraTracks(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", "/tmp/encoder")
+	t.Setenv("OUTPUT_HLS", "")
+
+	videoService := service.NewVideoService()
+	videoService.Video = domain.NewVideo()
+	videoService.Video.ID = "video"
+	videoService.Options = domain.JobOptions{
+		AudioLanguages: []string{"eng"},
+		AudioTracks:    []domain.AudioTrack{{FilePath: "dub/es.wav", Language: "es"}},
+		Subtitles:      []domain.SubtitleTrack{{FilePath: "subs/en.srt", Language: "en"}},
+	}
+
+	assert.Equal(t, []string{
+		"[type=video]/tmp/encoder/video.frag",
+		"[type=audio,language=eng]/tmp/encoder/video.frag",
+		"--use-segment-timeline",
+		"-o",
+		"/tmp/encoder/video",
+		"-f",
+		"[type=audio,+language=es,+role=dub]/tmp/encoder/video.audio/0_es.frag",
+		"[+format=webvtt,+language=en]/tmp/encoder/video.subtitles/0_en.vtt",
+	}, videoService.EncodeArgs())
+}
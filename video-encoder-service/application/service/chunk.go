@@ -0,0 +1,215 @@
+package service
+
+import (
+	"encoder/domain"
+	"encoder/framework/utils"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ChunkingRequested reports whether the renditions are encoded with
+// split-encode-stitch, either because the job asked for it or because the
+// video is longer than CHUNKED_ENCODING_MIN_DURATION seconds.
+func (v *VideoService) ChunkingRequested() bool {
+	if v.Options.Chunked != nil {
+		return true
+	}
+
+	threshold, err := strconv.ParseFloat(os.Getenv("CHUNKED_ENCODING_MIN_DURATION"), 64)
+	if err != nil || threshold <= 0 {
+		return false
+	}
+
+	return v.Video.PackagedDuration() >= threshold
+}
+
+// SplitChunks splits the video stream at key frames into chunks of about the
+// requested length, without re-encoding. Audio is packaged from the source.
+func (v *VideoService) SplitChunks() ([]string, error) {
+	seconds, err := utils.EnvInt("CHUNK_SECONDS", 60)
+	if err != nil {
+		return nil, err
+	}
+	if v.Options.Chunked != nil && v.Options.Chunked.Seconds > 0 {
+		seconds = v.Options.Chunked.Seconds
+	}
+
+	if err := os.MkdirAll(v.chunksPath(), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	err = runFFmpeg(
+		"-y", "-i", v.mp4Path(),
+		"-map", "0:v:0", "-an",
+		"-c", "copy",
+		"-f", "segment",
+		"-segment_time", strconv.Itoa(seconds),
+		"-reset_timestamps", "1",
+		fmt.Sprintf("%s/source_%%04d.mp4", v.chunksPath()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := filepath.Glob(fmt.Sprintf("%s/source_*.mp4", v.chunksPath()))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("video %v split into %d chunks", v.Video.ID, len(chunks))
+
+	return chunks, nil
+}
+
+// EncodeChunk encodes one chunk into every ladder rung.
+func (v *VideoService) EncodeChunk(index int, chunk string) error {
+	for _, rung := range v.Ladder {
+		if err := runFFmpeg(RenditionArgs(rung, chunk, v.chunkRenditionPath(rung, index))...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StitchChunks concatenates the encoded chunks of each rung back into a
+// single rendition, ready to be fragmented.
+func (v *VideoService) StitchChunks(count int) error {
+	if err := os.MkdirAll(v.renditionsPath(), os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, rung := range v.Ladder {
+		list := fmt.Sprintf("%s/%dp.txt", v.chunksPath(), rung.Height)
+		if err := os.WriteFile(list, []byte(ConcatList(v.chunkRenditionPaths(rung, count))), 0644); err != nil {
+			return err
+		}
+
+		err := runFFmpeg("-y", "-f", "concat", "-safe", "0", "-i", list, "-c", "copy", "-movflags", "+faststart", v.renditionPath(rung))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ConcatList builds the input file of ffmpeg's concat demuxer.
+func ConcatList(paths []string) string {
+	var list strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(path, "'", `'\''`))
+	}
+
+	return list.String()
+}
+
+// transcodeChunked runs split-encode-stitch. Every chunk is tracked as a
+// child job of the current one, and the renditions are only stitched once
+// all children completed.
+func (j *JobService) transcodeChunked() error {
+	chunks, err := j.VideoService.SplitChunks()
+	if err != nil {
+		return err
+	}
+
+	children := make([]*domain.Job, len(chunks))
+	for i := range chunks {
+		child, err := domain.NewChunkJob(j.Job, i)
+		if err != nil {
+			return err
+		}
+
+		if _, err := j.JobRepository.Insert(child); err != nil {
+			return err
+		}
+		children[i] = child
+	}
+
+	concurrency, err := utils.EnvInt("CHUNK_CONCURRENCY", max(1, runtime.NumCPU()/2))
+	if err != nil {
+		return err
+	}
+
+	in := make(chan int, len(chunks))
+	for i := range chunks {
+		in <- i
+	}
+	close(in)
+
+	results := make(chan error, len(chunks))
+	for worker := 0; worker < concurrency; worker++ {
+		go func() {
+			for i := range in {
+				results <- j.encodeChunk(children[i], chunks[i])
+			}
+		}()
+	}
+
+	var failed error
+	completed := 0
+	for range chunks {
+		if err := <-results; err != nil {
+			if failed == nil {
+				failed = err
+			}
+			continue
+		}
+
+		completed++
+		log.Printf("job %v: %d/%d chunks encoded", j.Job.ID, completed, len(chunks))
+	}
+
+	if failed != nil {
+		return failed
+	}
+
+	return j.VideoService.StitchChunks(len(chunks))
+}
+
+func (j *JobService) encodeChunk(child *domain.Job, chunk string) error {
+	child.Status = "ENCODING"
+	if _, err := j.JobRepository.Update(child); err != nil {
+		return err
+	}
+
+	if err := j.VideoService.EncodeChunk(child.ChunkIndex, chunk); err != nil {
+		encodeErr := fmt.Errorf("error encoding chunk %d: %w", child.ChunkIndex, err)
+
+		child.Status = "FAILED"
+		child.Error = err.Error()
+		if _, err := j.JobRepository.Update(child); err != nil {
+			return errors.Join(encodeErr, fmt.Errorf("error marking chunk %d failed: %w", child.ChunkIndex, err))
+		}
+
+		return encodeErr
+	}
+
+	child.Status = "COMPLETED"
+	_, err := j.JobRepository.Update(child)
+
+	return err
+}
+
+func (v *VideoService) chunksPath() string {
+	return fmt.Sprintf("%s/%s.chunks", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+}
+
+func (v *VideoService) chunkRenditionPath(rung domain.LadderRung, index int) string {
+	return fmt.Sprintf("%s/%dp_%04d.mp4", v.chunksPath(), rung.Height, index)
+}
+
+func (v *VideoService) chunkRenditionPaths(rung domain.LadderRung, count int) []string {
+	paths := make([]string, count)
+	for i := range paths {
+		paths[i] = v.chunkRenditionPath(rung, i)
+	}
+
+	return paths
+}
@@ -0,0 +1,31 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcatList(t *testing.T) {
+	list := service.ConcatList([]string{"/tmp/encoder/a.chunks/720p_0000.mp4", "/tmp/it's/720p_0001.mp4"})
+
+	assert.Equal(t, "file '/tmp/encoder/a.chunks/720p_0000.mp4'\nfile '/tmp/it'\\''s/720p_0001.mp4'\n", list)
+}
+
+func TestVideoService_ChunkingRequested(t *testing.T) {
+	t.Setenv("CHUNKED_ENCODING_MIN_DURATION", "3600")
+
+	videoService := service.NewVideoService()
+	videoService.Video = domain.NewVideo()
+	videoService.Video.MediaInfo.Duration = 600
+	assert.False(t, videoService.ChunkingRequested())
+
+	videoService.Video.MediaInfo.Duration = 7200
+	assert.True(t, videoService.ChunkingRequested())
+
+	videoService.Video.MediaInfo.Duration = 600
+	videoService.Options.Chunked = &domain.ChunkOptions{Seconds: 30}
+	assert.True(t, videoService.ChunkingRequested())
+}
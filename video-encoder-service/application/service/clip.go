@@ -0,0 +1,159 @@
+package service
+
+import (
+	"encoder/domain"
+	"encoder/framework/utils"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var clipName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Trim cuts the MP4 down to the requested ranges, joined in order, so the
+// packaged output only contains them. Clip times refer to the original
+// timeline, so preview clips are generated before trimming.
+func (v *VideoService) Trim() error {
+	if err := ValidateClipRanges(v.Options.Trim, v.Video.MediaInfo.Duration); err != nil {
+		return err
+	}
+
+	// Only the first audio track is kept, so other source languages can't be
+	// selected after trimming.
+	if len(v.Options.Subtitles) > 0 || len(v.Options.AudioTracks) > 0 || len(v.Options.AudioLanguages) > 0 {
+		return NewJobError(
+			ErrCodeInvalidRequest,
+			errors.New("trim can't be combined with external subtitles, audio tracks or audio languages"),
+		)
+	}
+
+	hasAudio := v.Video.MediaInfo.AudioTracks > 0
+	trimmed := fmt.Sprintf("%s.trim.mp4", v.mp4Path())
+
+	args := []string{"-y", "-i", v.mp4Path(), "-filter_complex", TrimFilter(v.Options.Trim, hasAudio), "-map", "[outv]"}
+	if hasAudio {
+		args = append(args, "-map", "[outa]", "-c:a", "aac", "-b:a", "160k")
+	}
+	args = append(args,
+		"-c:v", "libx264", "-preset", "medium", "-crf", "20", "-pix_fmt", "yuv420p",
+		"-movflags", "+faststart",
+		trimmed,
+	)
+
+	if err := runFFmpeg(args...); err != nil {
+		return err
+	}
+
+	if err := os.Rename(trimmed, v.mp4Path()); err != nil {
+		return err
+	}
+
+	duration := 0.0
+	for _, clip := range v.Options.Trim {
+		duration += clip.Duration()
+	}
+	v.Video.TrimmedDuration = duration
+	if _, err := v.VideoRepository.Update(v.Video); err != nil {
+		return err
+	}
+
+	log.Printf("video %v trimmed to %.2fs", v.Video.ID, duration)
+
+	return nil
+}
+
+// GenerateClips exports every requested range as a standalone MP4 next to the
+// encoded output and records their object paths on the video.
+func (v *VideoService) GenerateClips() error {
+	if err := ValidateClipRanges(v.Options.Clips, v.Video.MediaInfo.Duration); err != nil {
+		return err
+	}
+
+	folder := fmt.Sprintf("%s/clips", v.outputPath())
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+		return err
+	}
+
+	var clips []string
+	for i, clip := range v.Options.Clips {
+		target := fmt.Sprintf("%s/%s.mp4", folder, clip.FileName(i))
+
+		err := runFFmpeg(
+			"-y",
+			"-ss", utils.FormatFloat(clip.Start),
+			"-i", v.mp4Path(),
+			"-t", utils.FormatFloat(clip.Duration()),
+			"-map", "0:v:0", "-map", "0:a:0?",
+			"-c:v", "libx264", "-preset", "medium", "-crf", "22", "-pix_fmt", "yuv420p",
+			"-c:a", "aac", "-b:a", "128k",
+			"-movflags", "+faststart",
+			target,
+		)
+		if err != nil {
+			return err
+		}
+
+		clips = append(clips, objectName(target))
+	}
+
+	v.Video.Clips = clips
+	if _, err := v.VideoRepository.Update(v.Video); err != nil {
+		return err
+	}
+
+	log.Printf("%d clips generated for video %v", len(clips), v.Video.ID)
+
+	return nil
+}
+
+func ValidateClipRanges(clips []domain.ClipRange, duration float64) error {
+	names := map[string]bool{}
+
+	for i, clip := range clips {
+		if names[clip.FileName(i)] {
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("duplicate clip name %q", clip.FileName(i)))
+		}
+		names[clip.FileName(i)] = true
+
+		switch {
+		case clip.Start < 0 || clip.End <= clip.Start:
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("invalid clip range %v-%v", clip.Start, clip.End))
+		case duration > 0 && clip.End > duration:
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("clip range %v-%v ends after the video (%.2fs)", clip.Start, clip.End, duration))
+		case clip.Name != "" && !clipName.MatchString(clip.Name):
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("invalid clip name %q", clip.Name))
+		}
+	}
+
+	return nil
+}
+
+// TrimFilter cuts each range out of the video (and audio) and concatenates
+// the pieces into [outv] and [outa].
+func TrimFilter(clips []domain.ClipRange, hasAudio bool) string {
+	var filters, inputs []string
+
+	for i, clip := range clips {
+		start, end := utils.FormatFloat(clip.Start), utils.FormatFloat(clip.End)
+
+		filters = append(filters, fmt.Sprintf("[0:v]trim=start=%s:end=%s,setpts=PTS-STARTPTS[v%d]", start, end, i))
+		inputs = append(inputs, fmt.Sprintf("[v%d]", i))
+
+		if hasAudio {
+			filters = append(filters, fmt.Sprintf("[0:a]atrim=start=%s:end=%s,asetpts=PTS-STARTPTS[a%d]", start, end, i))
+			inputs = append(inputs, fmt.Sprintf("[a%d]", i))
+		}
+	}
+
+	audio, outputs := 0, "[outv]"
+	if hasAudio {
+		audio, outputs = 1, "[outv][outa]"
+	}
+
+	filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=1:a=%d%s", strings.Join(inputs, ""), len(clips), audio, outputs))
+
+	return strings.Join(filters, ";")
+}
@@ -0,0 +1,54 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimFilter(t *testing.T) {
+	clips := []domain.ClipRange{{Start: 0, End: 10}, {Start: 60.5, End: 70}}
+
+	assert.Equal(
+		t,
+		"[0:v]trim=start=0:end=10,setpts=PTS-STARTPTS[v0];"+
+			"[0:a]atrim=start=0:end=10,asetpts=PTS-STARTPTS[a0];"+
+			"[0:v]trim=start=60.5:end=70,setpts=PTS-STARTPTS[v1];"+
+			"[0:a]atrim=start=60.5:end=70,asetpts=PTS-STARTPTS[a1];"+
+			"[v0][a0][v1][a1]concat=n=2:v=1:a=1[outv][outa]",
+		service.TrimFilter(clips, true),
+	)
+
+	assert.Equal(
+		t,
+		"[0:v]trim=start=5:end=35,setpts=PTS-STARTPTS[v0];[v0]concat=n=1:v=1:a=0[outv]",
+		service.TrimFilter([]domain.ClipRange{{Start: 5, End: 35}}, false),
+	)
+}
+
+func TestValidateClipRanges(t *testing.T) {
+	assert.Nil(t, service.ValidateClipRanges([]domain.ClipRange{{Start: 0, End: 30, Name: "preview"}}, 120))
+
+	invalid := [][]domain.ClipRange{
+		{{Start: 30, End: 10}},
+		{{Start: 100, End: 130}},
+		{{Start: 0, End: 30, Name: "../preview"}},
+		{{Start: 0, End: 30, Name: "preview"}, {Start: 40, End: 60, Name: "preview"}},
+		{{Start: 0, End: 30}, {Start: 40, End: 60, Name: "clip_1"}},
+	}
+	for _, clips := range invalid {
+		assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateClipRanges(clips, 120)))
+	}
+}
+
+func TestTrim_RejectsAudioLanguages(t *testing.T) {
+	videoService := service.NewVideoService()
+	videoService.Video = domain.NewVideo()
+	videoService.Video.MediaInfo.Duration = 120
+	videoService.Options.Trim = []domain.ClipRange{{Start: 0, End: 30}}
+	videoService.Options.AudioLanguages = []string{"en", "pt"}
+
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(videoService.Trim()))
+}
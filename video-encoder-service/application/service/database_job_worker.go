@@ -0,0 +1,55 @@
+package service
+
+import (
+	"encoder/application/repository"
+	"errors"
+	"log"
+	"time"
+)
+
+// DatabaseJobWorker is the JobWorker of the database queue: it claims
+// PENDING jobs instead of reading RabbitMQ deliveries, and waits
+// pollInterval when there is none. Tenants already running maxPerTenant
+// jobs are skipped, and no job is claimed while health reports the database
+// as unhealthy.
+func DatabaseJobWorker(
+	returnChan chan JobWorkerResult,
+	jobService JobService,
+	workerID int,
+	pollInterval time.Duration,
+	maxPerTenant int,
+	health HealthStatus,
+) {
+	interval, err := heartbeatInterval()
+	if err != nil {
+		log.Fatalf("Error parsing JOB_HEARTBEAT_INTERVAL_SECONDS: %v", err)
+	}
+
+	for {
+		if !health.Healthy() {
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		job, err := jobService.JobRepository.Claim(workerName(workerID), maxPerTenant)
+		if errors.Is(err, repository.ErrConcurrentModification) {
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, repository.ErrNoPendingJob) {
+				log.Printf("error claiming a job: %v", err)
+			}
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		jobService.Job = job
+		jobService.VideoService.Video = job.Video
+
+		stopHeartbeat := jobService.keepAlive(interval)
+		err = jobService.Start()
+		stopHeartbeat()
+
+		returnChan <- returnJobResult(*job, nil, err)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"encoder/domain"
+	"encoder/framework/drm"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// FetchContentKey asks the configured key provider for the key of the video
+// resource. The key is kept in memory only and never persisted or logged.
+func (v *VideoService) FetchContentKey() error {
+	if err := ValidateEncryption(*v.Options.Encryption); err != nil {
+		return err
+	}
+
+	if v.KeyProvider == nil {
+		return NewJobError(ErrCodeInvalidRequest, errors.New("encryption requested but DRM_KEY_PROVIDER is not configured"))
+	}
+
+	key, err := v.KeyProvider.GetKey(v.Video.ResourceId)
+	if err != nil {
+		return NewJobError(ErrCodeEncryption, fmt.Errorf("error fetching content key: %w", err))
+	}
+
+	v.contentKey = key
+	log.Printf("content key %v fetched for video %v", key.KeyID, v.Video.ID)
+
+	return nil
+}
+
+func ValidateEncryption(encryption domain.Encryption) error {
+	switch encryption.Scheme {
+	case "", "cenc", "cbcs":
+		return nil
+	}
+
+	return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("unsupported encryption scheme %q", encryption.Scheme))
+}
+
+// EncryptionArgs returns the mp4dash flags that encrypt the output and write
+// the PSSH boxes and ContentProtection elements of each requested DRM system.
+func EncryptionArgs(encryption domain.Encryption, key drm.ContentKey, resourceID string) []string {
+	scheme := encryption.Scheme
+	if scheme == "" {
+		scheme = "cenc"
+	}
+
+	args := []string{
+		fmt.Sprintf("--encryption-key=%s:%s", key.KeyID, key.Key),
+		"--encryption-cenc-scheme=" + scheme,
+	}
+
+	if encryption.Widevine {
+		args = append(args,
+			"--widevine",
+			fmt.Sprintf("--widevine-header=provider:%s#content_id:%s", os.Getenv("DRM_WIDEVINE_PROVIDER"), hex.EncodeToString([]byte(resourceID))),
+		)
+	}
+
+	if encryption.PlayReady {
+		args = append(args, "--playready")
+		if laURL := os.Getenv("DRM_PLAYREADY_LA_URL"); laURL != "" {
+			args = append(args, "--playready-header=LA_URL:"+laURL)
+		}
+	}
+
+	return args
+}
@@ -0,0 +1,33 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"encoder/framework/drm"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptionArgs(t *testing.T) {
+	t.Setenv("DRM_WIDEVINE_PROVIDER", "codeflix")
+	t.Setenv("DRM_PLAYREADY_LA_URL", "https://license.example.com/rightsmanager.asmx")
+
+	key := drm.ContentKey{KeyID: "0123456789abcdef0123456789abcdef", Key: "fedcba9876543210fedcba9876543210"}
+	encryption := domain.Encryption{Scheme: "cbcs", Widevine: true, PlayReady: true}
+
+	assert.Equal(t, []string{
+		"--encryption-key=0123456789abcdef0123456789abcdef:fedcba9876543210fedcba9876543210",
+		"--encryption-cenc-scheme=cbcs",
+		"--widevine",
+		"--widevine-header=provider:codeflix#content_id:616263",
+		"--playready",
+		"--playready-header=LA_URL:https://license.example.com/rightsmanager.asmx",
+	}, service.EncryptionArgs(encryption, key, "abc"))
+}
+
+func TestValidateEncryption(t *testing.T) {
+	assert.Nil(t, service.ValidateEncryption(domain.Encryption{}))
+	assert.Nil(t, service.ValidateEncryption(domain.Encryption{Scheme: "cbcs"}))
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateEncryption(domain.Encryption{Scheme: "aes"})))
+}
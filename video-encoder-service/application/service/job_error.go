@@ -0,0 +1,59 @@
+package service
+
+import (
+	"encoder/application/repository"
+	"errors"
+)
+
+const (
+	ErrCodeInvalidSource          = "INVALID_SOURCE"
+	ErrCodeInvalidRequest         = "INVALID_REQUEST"
+	ErrCodeEncryption             = "ENCRYPTION_ERROR"
+	ErrCodeJobNotFound            = "JOB_NOT_FOUND"
+	ErrCodeVideoNotFound          = "VIDEO_NOT_FOUND"
+	ErrCodeConcurrentModification = "CONCURRENT_MODIFICATION"
+	ErrCodeDatabase               = "DATABASE_ERROR"
+	ErrCodeJobAbandoned           = "JOB_ABANDONED"
+)
+
+// JobError tags a pipeline error with a code that is sent along with the
+// failure notification, so consumers can react without parsing messages.
+type JobError struct {
+	Code string
+	Err  error
+}
+
+func NewJobError(code string, err error) *JobError {
+	return &JobError{Code: code, Err: err}
+}
+
+func (e *JobError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code of a JobError, or the code matching a
+// repository error.
+func ErrorCode(err error) string {
+	var jobError *JobError
+	if errors.As(err, &jobError) {
+		return jobError.Code
+	}
+
+	var databaseError *repository.DatabaseError
+	switch {
+	case errors.Is(err, repository.ErrJobNotFound):
+		return ErrCodeJobNotFound
+	case errors.Is(err, repository.ErrVideoNotFound):
+		return ErrCodeVideoNotFound
+	case errors.Is(err, repository.ErrConcurrentModification):
+		return ErrCodeConcurrentModification
+	case errors.As(err, &databaseError):
+		return ErrCodeDatabase
+	}
+
+	return ""
+}
@@ -0,0 +1,20 @@
+package service_test
+
+import (
+	"encoder/application/repository"
+	"encoder/application/service"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCode(t *testing.T) {
+	assert.Equal(t, service.ErrCodeInvalidSource, service.ErrorCode(service.NewJobError(service.ErrCodeInvalidSource, errors.New("bad"))))
+	assert.Equal(t, service.ErrCodeJobNotFound, service.ErrorCode(fmt.Errorf("retry: %w", repository.ErrJobNotFound)))
+	assert.Equal(t, service.ErrCodeVideoNotFound, service.ErrorCode(repository.ErrVideoNotFound))
+	assert.Equal(t, service.ErrCodeConcurrentModification, service.ErrorCode(repository.ErrConcurrentModification))
+	assert.Equal(t, service.ErrCodeDatabase, service.ErrorCode(&repository.DatabaseError{Op: "find job", Err: errors.New("connection refused")}))
+	assert.Equal(t, "", service.ErrorCode(errors.New("unknown")))
+}
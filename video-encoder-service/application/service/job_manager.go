@@ -3,12 +3,17 @@ package service
 import (
 	"encoder/application/repository"
 	"encoder/domain"
+	"encoder/framework/database"
+	"encoder/framework/drm"
 	"encoder/framework/queue"
+	"encoder/framework/utils"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
 	"gorm.io/gorm"
@@ -20,11 +25,21 @@ type JobManager struct {
 	MessageChannel   chan amqp.Delivery
 	JobReturnChannel chan JobWorkerResult
 	RabbitMQ         *queue.RabbitMQ
+	Publisher        Publisher
+	QueueDriver      string
+	Health           HealthStatus
+}
+
+// HealthStatus tells whether the database is reachable. Workers stop pulling
+// new jobs while it is not.
+type HealthStatus interface {
+	Healthy() bool
 }
 
 type JobNotificationError struct {
 	Message string `json:"message"`
 	Error   string `json:"error"`
+	Code    string `json:"code,omitempty"`
 }
 
 var mutex sync.Mutex
@@ -41,12 +56,34 @@ func NewJobManager(
 		MessageChannel:   messageChannel,
 		JobReturnChannel: jobReturnChannel,
 		RabbitMQ:         rabbitMQ,
+		Publisher:        rabbitMQ,
+		QueueDriver:      QueueDriverRabbitMQ,
+	}
+}
+
+// NewDatabaseJobManager builds a JobManager that claims jobs from the jobs
+// table and writes notifications to the outbox, without RabbitMQ.
+func NewDatabaseJobManager(db *gorm.DB, jobReturnChannel chan JobWorkerResult) *JobManager {
+	return &JobManager{
+		DB:               db,
+		Domain:           domain.Job{},
+		JobReturnChannel: jobReturnChannel,
+		Publisher: OutboxPublisher{
+			NotificationRepository: repository.NewNotificationRepository(db),
+		},
+		QueueDriver: QueueDriverDatabase,
 	}
 }
 
 func (j *JobManager) Start(ch *amqp.Channel) {
+	keyProvider, err := drm.NewKeyProvider()
+	if err != nil {
+		log.Fatalf("Error creating the DRM key provider: %v", err)
+	}
+
 	videoService := VideoService{
 		VideoRepository: repository.VideoRepositoryDb{Db: j.DB},
+		KeyProvider:     keyProvider,
 	}
 
 	jobService := JobService{
@@ -54,14 +91,84 @@ func (j *JobManager) Start(ch *amqp.Channel) {
 		VideoService:  videoService,
 	}
 
+	var jobQueue JobQueue = RabbitMQJobQueue{
+		Publisher: j.Publisher,
+		QueueName: os.Getenv("RABBITMQ_CONSUMER_QUEUE_NAME"),
+	}
+	if j.QueueDriver == QueueDriverDatabase {
+		jobQueue = DatabaseJobQueue{
+			JobRepository:   jobService.JobRepository,
+			VideoRepository: videoService.VideoRepository,
+		}
+	}
+
+	reaper, err := NewJobReaper(jobService.JobRepository, j.Publisher, jobQueue)
+	if err != nil {
+		log.Fatalf("Error creating the job reaper: %v", err)
+	}
+
+	reaperInterval, err := utils.EnvInt("JOB_REAPER_INTERVAL_SECONDS", 60)
+	if err != nil {
+		log.Fatalf("Error parsing JOB_REAPER_INTERVAL_SECONDS: %v", err)
+	}
+
+	go reaper.Run(time.Duration(reaperInterval) * time.Second)
+
+	retention, err := NewRetention(jobService.JobRepository, videoService.VideoRepository)
+	if err != nil {
+		log.Fatalf("Error creating the retention policy: %v", err)
+	}
+
+	if retention.MaxAge > 0 {
+		retentionInterval, err := utils.EnvInt("RETENTION_INTERVAL_HOURS", 24)
+		if err != nil {
+			log.Fatalf("Error parsing RETENTION_INTERVAL_HOURS: %v", err)
+		}
+
+		go retention.Run(time.Duration(retentionInterval) * time.Hour)
+	}
+
+	if j.Health == nil {
+		j.Health = j.startHealthChecker()
+	}
+
 	maxConversionConcurrency, err := strconv.Atoi(os.Getenv("MAX_CONVERSION_CONCURRENCY"))
 	if err != nil {
 		log.Fatalf("Error to parse MAX_CONVERSION_CONCURRENCY")
 	}
 
+	pollInterval, err := utils.EnvInt("JOB_QUEUE_POLL_INTERVAL_SECONDS", 5)
+	if err != nil {
+		log.Fatalf("Error parsing JOB_QUEUE_POLL_INTERVAL_SECONDS: %v", err)
+	}
+
+	maxPerTenant, err := utils.EnvInt("JOB_MAX_PER_TENANT", 0)
+	if err != nil {
+		log.Fatalf("Error parsing JOB_MAX_PER_TENANT: %v", err)
+	}
+
+	if j.QueueDriver == QueueDriverDatabase {
+		for workerCount := 0; workerCount < maxConversionConcurrency; workerCount++ {
+			go DatabaseJobWorker(
+				j.JobReturnChannel,
+				jobService,
+				workerCount,
+				time.Duration(pollInterval)*time.Second,
+				maxPerTenant,
+				j.Health,
+			)
+		}
+
+		for jobResult := range j.JobReturnChannel {
+			j.handleResult(jobResult, ch)
+		}
+		return
+	}
+
+	dispatchChannel := make(chan amqp.Delivery)
 	for workerCount := 0; workerCount < maxConversionConcurrency; workerCount++ {
 		go JobWorker(
-			j.MessageChannel,
+			dispatchChannel,
 			j.JobReturnChannel,
 			jobService,
 			j.Domain,
@@ -69,19 +176,135 @@ func (j *JobManager) Start(ch *amqp.Channel) {
 		)
 	}
 
-	for jobResult := range j.JobReturnChannel {
-		if jobResult.Error != nil {
-			err = j.checkParseErrors(jobResult)
+	maxPending, err := schedulerMaxPending(maxConversionConcurrency)
+	if err != nil {
+		log.Fatalf("Error parsing JOB_SCHEDULER_MAX_PENDING: %v", err)
+	}
+
+	scheduler := NewFairScheduler[amqp.Delivery](maxPerTenant)
+	scheduler.MaxPending = maxPending
+
+	j.dispatch(
+		scheduler,
+		dispatchChannel,
+		ch,
+		time.Duration(pollInterval)*time.Second,
+	)
+}
+
+// schedulerMaxPending reads JOB_SCHEDULER_MAX_PENDING, twice the number of
+// workers by default.
+func schedulerMaxPending(workers int) (int, error) {
+	return utils.EnvInt("JOB_SCHEDULER_MAX_PENDING", 2*workers)
+}
+
+// DispatchPrefetch is the RabbitMQ prefetch count matching the dispatcher:
+// the messages the scheduler may hold plus one running on every worker, so
+// the broker never delivers more than the dispatcher can take.
+func DispatchPrefetch() (int, error) {
+	workers, err := strconv.Atoi(os.Getenv("MAX_CONVERSION_CONCURRENCY"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid MAX_CONVERSION_CONCURRENCY value: %w", err)
+	}
+
+	maxPending, err := schedulerMaxPending(workers)
+	if err != nil {
+		return 0, err
+	}
+
+	return maxPending + workers, nil
+}
+
+func (j *JobManager) startHealthChecker() HealthStatus {
+	interval, err := utils.EnvInt("DB_HEALTH_CHECK_INTERVAL_SECONDS", 10)
+	if err != nil {
+		log.Fatalf("Error parsing DB_HEALTH_CHECK_INTERVAL_SECONDS: %v", err)
+	}
+
+	timeout, err := utils.EnvInt("DB_HEALTH_CHECK_TIMEOUT_SECONDS", 5)
+	if err != nil {
+		log.Fatalf("Error parsing DB_HEALTH_CHECK_TIMEOUT_SECONDS: %v", err)
+	}
+
+	checker := database.NewHealthChecker(j.DB, time.Duration(timeout)*time.Second)
+	go checker.Run(time.Duration(interval) * time.Second)
+
+	return checker
+}
+
+// dispatch hands the incoming messages to the workers in the order of the
+// scheduler, as workers become free. Messages of a tenant at its cap wait in
+// the scheduler, unacknowledged, while other tenants go ahead. Nothing is
+// pulled while the scheduler is full, and deliveries of a tenant already
+// holding its share of it are sent to the delay queue, so the messages
+// buffered stay bounded and can't all belong to a capped tenant. While the database is
+// unhealthy nothing is pulled or dispatched, and the health is checked again
+// every recheckInterval.
+func (j *JobManager) dispatch(
+	scheduler *FairScheduler[amqp.Delivery],
+	dispatchChannel chan amqp.Delivery,
+	ch *amqp.Channel,
+	recheckInterval time.Duration,
+) {
+	incoming := j.MessageChannel
+
+	for {
+		var out chan amqp.Delivery
+		var recheck <-chan time.Time
+		next, ok := scheduler.Peek()
+
+		pull := incoming
+		if scheduler.Full() {
+			pull = nil
+		}
+		if j.Health.Healthy() {
+			if ok {
+				out = dispatchChannel
+			}
 		} else {
-			err = j.notifySuccess(jobResult, ch)
+			pull = nil
+			recheck = time.After(recheckInterval)
 		}
 
-		if err != nil {
-			jobResult.Message.Reject(false)
+		select {
+		case <-recheck:
+		case message, ok := <-pull:
+			if !ok {
+				incoming = nil
+				continue
+			}
+			tenant, priority := messageScheduling(message.Body, int(message.Priority))
+			if !scheduler.Accepts(tenant) {
+				err := j.RabbitMQ.Defer(message)
+				if err == nil {
+					continue
+				}
+				log.Printf("error deferring message %v of tenant %v: %v", message.MessageId, tenant, err)
+			}
+			scheduler.Push(tenant, priority, message)
+		case out <- next:
+			scheduler.Pop()
+		case jobResult := <-j.JobReturnChannel:
+			tenant, _ := messageScheduling(jobResult.Message.Body, 0)
+			scheduler.Done(tenant)
+			j.handleResult(jobResult, ch)
 		}
 	}
 }
 
+func (j *JobManager) handleResult(jobResult JobWorkerResult, ch *amqp.Channel) {
+	var err error
+	if jobResult.Error != nil {
+		err = j.checkParseErrors(jobResult)
+	} else {
+		err = j.notifySuccess(jobResult, ch)
+	}
+
+	if err != nil && jobResult.Message != nil {
+		jobResult.Message.Reject(false)
+	}
+}
+
 func (j *JobManager) notifySuccess(jobResult JobWorkerResult, ch *amqp.Channel) error {
 	mutex.Lock()
 	jobJson, err := json.Marshal(jobResult.Job)
@@ -95,22 +318,37 @@ func (j *JobManager) notifySuccess(jobResult JobWorkerResult, ch *amqp.Channel)
 		return err
 	}
 
+	if jobResult.Message == nil {
+		return nil
+	}
+
 	return jobResult.Message.Ack(false)
 }
 
 func (j *JobManager) checkParseErrors(jobResult JobWorkerResult) error {
+	var messageID string
+	var body []byte
+	if jobResult.Message != nil {
+		messageID = jobResult.Message.MessageId
+		body = jobResult.Message.Body
+	} else if jobResult.Job.ID != "" {
+		messageID = jobResult.Job.ID
+		body, _ = JobMessage(&jobResult.Job)
+	}
+
 	if jobResult.Job.ID != "" {
 		log.Printf(
 			"MessageID: %v | VideoID: %v | JobID: %v | Status: %v\n",
-			jobResult.Message.MessageId, jobResult.Job.Video.ID, jobResult.Job.ID, jobResult.Job.Status,
+			messageID, jobResult.Job.Video.ID, jobResult.Job.ID, jobResult.Job.Status,
 		)
 	} else {
-		log.Printf("MessageID: %v | Error: %v\n", jobResult.Message.MessageId, jobResult.Error.Error())
+		log.Printf("MessageID: %v | Error: %v\n", messageID, jobResult.Error.Error())
 	}
 
 	errorMessage := JobNotificationError{
-		Message: string(jobResult.Message.Body),
+		Message: string(body),
 		Error:   jobResult.Error.Error(),
+		Code:    ErrorCode(jobResult.Error),
 	}
 
 	jobJson, err := json.Marshal(errorMessage)
@@ -122,11 +360,15 @@ func (j *JobManager) checkParseErrors(jobResult JobWorkerResult) error {
 		return err
 	}
 
+	if jobResult.Message == nil {
+		return nil
+	}
+
 	return jobResult.Message.Reject(false)
 }
 
 func (j *JobManager) notify(jobJson []byte) error {
-	return j.RabbitMQ.Notify(
+	return j.Publisher.Notify(
 		string(jobJson),
 		"application/json",
 		os.Getenv("RABBITMQ_NOTIFICATION_EX"),
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"encoder/application/repository"
+	"encoder/domain"
+	"encoder/framework/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+const (
+	QueueDriverRabbitMQ = "rabbitmq"
+	QueueDriverDatabase = "database"
+)
+
+// QueueDriver returns where jobs come from, set by JOB_QUEUE_DRIVER:
+// "rabbitmq" (the default) or "database" for deployments without a broker.
+func QueueDriver() (string, error) {
+	driver := utils.EnvString("JOB_QUEUE_DRIVER", QueueDriverRabbitMQ)
+	if driver != QueueDriverRabbitMQ && driver != QueueDriverDatabase {
+		return "", fmt.Errorf("unknown JOB_QUEUE_DRIVER %q", driver)
+	}
+
+	return driver, nil
+}
+
+// MaxPriority is the highest priority a message can carry, the AMQP limit.
+const MaxPriority = 255
+
+// AttemptHeader carries the attempt count of a requeued message. It is kept
+// out of the body, which clients write.
+const AttemptHeader = "x-attempt"
+
+// JobQueue takes encode messages in. Requeue puts the message of an
+// abandoned job back with its attempt count.
+type JobQueue interface {
+	Enqueue(message []byte) error
+	Requeue(message []byte, attempt int) error
+}
+
+// RabbitMQJobQueue publishes encode messages to the queue JobWorker
+// consumes.
+type RabbitMQJobQueue struct {
+	Publisher Publisher
+	QueueName string
+}
+
+// priorityPublisher is implemented by publishers that can set the AMQP
+// priority and headers of a message, like queue.RabbitMQ.
+type priorityPublisher interface {
+	Publish(message string, contentType string, exchange string, routingKey string, priority uint8, headers map[string]any) error
+}
+
+func (q RabbitMQJobQueue) Enqueue(message []byte) error {
+	if publisher, ok := q.Publisher.(priorityPublisher); ok {
+		return q.publish(publisher, message, nil)
+	}
+
+	return q.Publisher.Notify(string(message), "application/json", "", q.QueueName)
+}
+
+func (q RabbitMQJobQueue) Requeue(message []byte, attempt int) error {
+	publisher, ok := q.Publisher.(priorityPublisher)
+	if !ok {
+		return fmt.Errorf("publisher %T can't set the %v header", q.Publisher, AttemptHeader)
+	}
+
+	return q.publish(publisher, message, map[string]any{AttemptHeader: int32(attempt)})
+}
+
+func (q RabbitMQJobQueue) publish(publisher priorityPublisher, message []byte, headers map[string]any) error {
+	_, priority := messageScheduling(message, 0)
+
+	return publisher.Publish(string(message), "application/json", "", q.QueueName, uint8(min(max(priority, 0), MaxPriority)), headers)
+}
+
+// DatabaseJobQueue stores encode messages as PENDING jobs, claimed by
+// DatabaseJobWorker.
+type DatabaseJobQueue struct {
+	JobRepository   repository.JobRepository
+	VideoRepository repository.VideoRepository
+}
+
+func (q DatabaseJobQueue) Enqueue(message []byte) error {
+	_, err := q.Insert(message)
+
+	return err
+}
+
+func (q DatabaseJobQueue) Requeue(message []byte, attempt int) error {
+	_, err := q.insert(message, attempt)
+
+	return err
+}
+
+// Insert creates the video and the PENDING job of an encode message.
+func (q DatabaseJobQueue) Insert(message []byte) (*domain.Job, error) {
+	return q.insert(message, 0)
+}
+
+func (q DatabaseJobQueue) insert(message []byte, attempt int) (*domain.Job, error) {
+	video, options, err := parseJobMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	options.Attempt = attempt
+
+	if _, err := q.VideoRepository.Insert(video); err != nil {
+		return nil, err
+	}
+
+	job, err := domain.NewJob(os.Getenv("OUTPUT_BUCKET_NAME"), "PENDING", video)
+	if err != nil {
+		return nil, err
+	}
+	job.Options = options
+	job.Priority = options.Priority
+	job.Tenant = options.Tenant
+
+	return q.JobRepository.Insert(job)
+}
+
+// OutboxPublisher stores notifications in the outbox table instead of
+// sending them to RabbitMQ.
+type OutboxPublisher struct {
+	NotificationRepository repository.NotificationRepository
+}
+
+func (p OutboxPublisher) Notify(message string, contentType string, exchange string, routingKey string) error {
+	_, err := p.NotificationRepository.Insert(domain.NewNotification(message, contentType, exchange, routingKey))
+
+	return err
+}
+
+// parseJobMessage reads the video and the options of an encode message. The
+// video gets a new id and the attempt count is reset, it never comes from the
+// body.
+func parseJobMessage(message []byte) (*domain.Video, domain.JobOptions, error) {
+	var options domain.JobOptions
+
+	if err := utils.IsJson(string(message)); err != nil {
+		return nil, options, err
+	}
+
+	video := domain.NewVideo()
+	if err := json.Unmarshal(message, video); err != nil {
+		return nil, options, err
+	}
+
+	if err := json.Unmarshal(message, &options); err != nil {
+		return nil, options, err
+	}
+	options.Attempt = 0
+
+	if options.Priority < 0 || options.Priority > MaxPriority {
+		return nil, options, NewJobError(ErrCodeInvalidRequest, fmt.Errorf("priority must be between 0 and %d", MaxPriority))
+	}
+	if err := ValidateSubtitles(options.Subtitles); err != nil {
+		return nil, options, err
+	}
+	if err := ValidateAudioTracks(options.AudioTracks); err != nil {
+		return nil, options, err
+	}
+	if options.Encryption != nil {
+		if err := ValidateEncryption(*options.Encryption); err != nil {
+			return nil, options, err
+		}
+	}
+
+	video.ID = uuid.New().String()
+	if err := video.Validate(); err != nil {
+		return nil, options, err
+	}
+
+	return video, options, nil
+}
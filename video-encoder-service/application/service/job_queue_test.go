@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"encoder/application/repository"
+	"encoder/application/service"
+	"encoder/framework/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseJobQueue_Insert(t *testing.T) {
+	t.Setenv("OUTPUT_BUCKET_NAME", "output")
+
+	db := database.NewDbTest()
+	jobQueue := service.DatabaseJobQueue{
+		JobRepository:   repository.NewJobRepository(db),
+		VideoRepository: repository.NewVideoRepository(db),
+	}
+
+	message := `{"resource_id": "5f4e5c43-6c3a-4a34-9d4e-2b1b0f0e9a11", "file_path": "movie.mp4", "ladder": "per-title"}`
+	job, err := jobQueue.Insert([]byte(message))
+	require.Nil(t, err)
+
+	claimed, err := jobQueue.JobRepository.Claim("worker-1", 0)
+	require.Nil(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+	assert.Equal(t, "output", claimed.OutputBucketPath)
+	assert.Equal(t, "per-title", claimed.Options.Ladder)
+	assert.Equal(t, "movie.mp4", claimed.Video.FilePath)
+}
+
+func TestDatabaseJobQueue_Attempt(t *testing.T) {
+	db := database.NewDbTest()
+	jobQueue := service.DatabaseJobQueue{
+		JobRepository:   repository.NewJobRepository(db),
+		VideoRepository: repository.NewVideoRepository(db),
+	}
+
+	message := `{"resource_id": "5f4e5c43-6c3a-4a34-9d4e-2b1b0f0e9a11", "file_path": "movie.mp4", "attempt": 2}`
+	job, err := jobQueue.Insert([]byte(message))
+	require.Nil(t, err)
+	assert.Equal(t, 0, job.Options.Attempt)
+
+	require.Nil(t, jobQueue.Requeue([]byte(message), 1))
+	claimed, err := jobQueue.JobRepository.Claim("worker-1", 0)
+	require.Nil(t, err)
+	claimed, err = jobQueue.JobRepository.Claim("worker-1", 0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, claimed.Options.Attempt)
+}
+
+func TestDatabaseJobQueue_InsertInvalidMessage(t *testing.T) {
+	db := database.NewDbTest()
+	jobQueue := service.DatabaseJobQueue{
+		JobRepository:   repository.NewJobRepository(db),
+		VideoRepository: repository.NewVideoRepository(db),
+	}
+
+	assert.NotNil(t, jobQueue.Enqueue([]byte("not json")))
+	assert.NotNil(t, jobQueue.Enqueue([]byte(`{"resource_id": "5f4e5c43-6c3a-4a34-9d4e-2b1b0f0e9a11"}`)))
+
+	for _, options := range []string{
+		`"subtitles": [{"file_path": "subs/en.txt", "language": "en"}]`,
+		`"audio_tracks": [{"file_path": "dub/es.wav", "language": "es", "role": "karaoke"}]`,
+		`"encryption": {"scheme": "cens"}`,
+	} {
+		message := `{"resource_id": "5f4e5c43-6c3a-4a34-9d4e-2b1b0f0e9a11", "file_path": "movie.mp4", ` + options + `}`
+		err := jobQueue.Enqueue([]byte(message))
+		assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(err), options)
+	}
+
+	page, err := jobQueue.JobRepository.List(repository.JobFilter{})
+	require.Nil(t, err)
+	assert.Empty(t, page.Items)
+}
+
+func TestOutboxPublisher_Notify(t *testing.T) {
+	db := database.NewDbTest()
+	notificationRepo := repository.NewNotificationRepository(db)
+	publisher := service.OutboxPublisher{NotificationRepository: notificationRepo}
+
+	require.Nil(t, publisher.Notify(`{"status":"COMPLETED"}`, "application/json", "amq.direct", "jobs"))
+
+	notifications, err := notificationRepo.ListUnpublished(10)
+	require.Nil(t, err)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, "amq.direct", notifications[0].Exchange)
+}
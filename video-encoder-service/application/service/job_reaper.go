@@ -0,0 +1,203 @@
+package service
+
+import (
+	"encoder/application/repository"
+	"encoder/domain"
+	"encoder/framework/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+)
+
+const (
+	ReapPolicyFail    = "fail"
+	ReapPolicyRequeue = "requeue"
+)
+
+// Publisher sends a message to an exchange, as queue.RabbitMQ.Notify does.
+type Publisher interface {
+	Notify(message string, contentType string, exchange string, routingKey string) error
+}
+
+// JobReaper finds jobs whose worker stopped heart-beating, marks them and
+// their unfinished chunk jobs FAILED and, with the requeue policy, puts their
+// message back in the queue until MaxAttempts runs were made. Working files
+// are only removed for the workers of this process, the others may run on
+// another host.
+type JobReaper struct {
+	JobRepository repository.JobRepository
+	Publisher     Publisher
+	Queue         JobQueue
+	StaleAfter    time.Duration
+	Policy        string
+	MaxAttempts   int
+}
+
+func NewJobReaper(jobRepository repository.JobRepository, publisher Publisher, queue JobQueue) (*JobReaper, error) {
+	staleAfter, err := utils.EnvInt("JOB_HEARTBEAT_TIMEOUT_SECONDS", 300)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAttempts, err := utils.EnvInt("JOB_REAPER_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := utils.EnvString("JOB_REAPER_POLICY", ReapPolicyFail)
+	if policy != ReapPolicyFail && policy != ReapPolicyRequeue {
+		return nil, fmt.Errorf("unknown JOB_REAPER_POLICY %q", policy)
+	}
+
+	return &JobReaper{
+		JobRepository: jobRepository,
+		Publisher:     publisher,
+		Queue:         queue,
+		StaleAfter:    time.Duration(staleAfter) * time.Second,
+		Policy:        policy,
+		MaxAttempts:   maxAttempts,
+	}, nil
+}
+
+func (r *JobReaper) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := r.Reap(); err != nil {
+			log.Printf("error reaping stale jobs: %v", err)
+		}
+	}
+}
+
+// Reap handles the stale jobs and returns how many were reaped. Jobs updated
+// by someone else in the meantime, e.g. another reaper, are skipped.
+func (r *JobReaper) Reap() (int, error) {
+	page, err := r.JobRepository.List(repository.JobFilter{
+		HeartbeatBefore: time.Now().Add(-r.StaleAfter),
+		Limit:           repository.MaxPageSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reaped := 0
+	for _, job := range page.Items {
+		err := r.reap(job)
+		if errors.Is(err, repository.ErrConcurrentModification) {
+			continue
+		}
+		if err != nil {
+			log.Printf("error reaping job %v: %v", job.ID, err)
+			continue
+		}
+
+		reaped++
+	}
+
+	return reaped, nil
+}
+
+func (r *JobReaper) reap(job *domain.Job) error {
+	requeue := r.Policy == ReapPolicyRequeue && job.Options.Attempt+1 < r.MaxAttempts
+
+	job.Status = "FAILED"
+	job.Error = fmt.Sprintf("worker %v stopped responding while the job was running", job.WorkerID)
+	if _, err := r.JobRepository.Update(job); err != nil {
+		return err
+	}
+
+	log.Printf("job %v abandoned by worker %v", job.ID, job.WorkerID)
+
+	if err := r.failChunkJobs(job); err != nil {
+		log.Printf("error failing the chunk jobs of job %v: %v", job.ID, err)
+	}
+
+	if job.Video != nil && ownWorker(job.WorkerID) {
+		videoService := VideoService{Video: job.Video}
+		if err := videoService.RemoveWorkingFiles(); err != nil {
+			log.Printf("error removing working files of job %v: %v", job.ID, err)
+		}
+	}
+
+	message, err := JobMessage(job)
+	if err != nil {
+		return err
+	}
+
+	notification, err := json.Marshal(JobNotificationError{
+		Message: string(message),
+		Error:   job.Error,
+		Code:    ErrCodeJobAbandoned,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.Publisher.Notify(
+		string(notification),
+		"application/json",
+		os.Getenv("RABBITMQ_NOTIFICATION_EX"),
+		os.Getenv("RABBITMQ_NOTIFICATION_ROUTING_KEY"),
+	)
+	if err != nil {
+		return err
+	}
+
+	if !requeue {
+		return nil
+	}
+
+	log.Printf("job %v requeued, attempt %d", job.ID, job.Options.Attempt+2)
+
+	return r.Queue.Requeue(message, job.Options.Attempt+1)
+}
+
+// failChunkJobs marks the unfinished chunk jobs of a reaped job FAILED. They
+// don't heartbeat, their parent's worker ran them.
+func (r *JobReaper) failChunkJobs(job *domain.Job) error {
+	page, err := r.JobRepository.List(repository.JobFilter{ParentJobId: job.ID, Limit: repository.MaxPageSize})
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range page.Items {
+		if slices.Contains(repository.TerminalJobStatuses, chunk.Status) {
+			continue
+		}
+
+		chunk.Status = "FAILED"
+		chunk.Error = job.Error
+		if _, err := r.JobRepository.Update(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// JobMessage rebuilds the encode message a job was created from.
+func JobMessage(job *domain.Job) ([]byte, error) {
+	options, err := json.Marshal(job.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	message := map[string]any{}
+	if err := json.Unmarshal(options, &message); err != nil {
+		return nil, err
+	}
+
+	delete(message, "attempt")
+
+	if job.Video != nil {
+		message["resource_id"] = job.Video.ResourceId
+		message["file_path"] = job.Video.FilePath
+	}
+
+	return json.Marshal(message)
+}
@@ -0,0 +1,231 @@
+package service_test
+
+import (
+	"encoder/application/repository"
+	"encoder/application/service"
+	"encoder/domain"
+	"encoder/framework/database"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type publishedMessage struct {
+	Body       string
+	Exchange   string
+	RoutingKey string
+	Headers    map[string]any
+}
+
+type fakePublisher struct {
+	Messages []publishedMessage
+}
+
+func (p *fakePublisher) Notify(message string, contentType string, exchange string, routingKey string) error {
+	p.Messages = append(p.Messages, publishedMessage{Body: message, Exchange: exchange, RoutingKey: routingKey})
+	return nil
+}
+
+func (p *fakePublisher) Publish(message string, contentType string, exchange string, routingKey string, priority uint8, headers map[string]any) error {
+	p.Messages = append(p.Messages, publishedMessage{Body: message, Exchange: exchange, RoutingKey: routingKey, Headers: headers})
+	return nil
+}
+
+// ownWorkerName is the name a worker of this process gets.
+func ownWorkerName(t *testing.T) string {
+	hostname, err := os.Hostname()
+	require.Nil(t, err)
+
+	return fmt.Sprintf("%s-%d-0", hostname, os.Getpid())
+}
+
+func insertStaleJob(t *testing.T, jobRepo *repository.JobRepositoryDb, videoRepo *repository.VideoRepositoryDb) *domain.Job {
+	video := domain.NewVideo()
+	video.ID = uuid.New().String()
+	video.ResourceId = uuid.New().String()
+	video.FilePath = "movie.mp4"
+	_, err := videoRepo.Insert(video)
+	require.Nil(t, err)
+
+	job, err := domain.NewJob("bucket", "ENCODING", video)
+	require.Nil(t, err)
+	job.WorkerID = ownWorkerName(t)
+	heartbeat := time.Now().Add(-time.Hour)
+	job.HeartbeatAt = &heartbeat
+	job.Options.Ladder = "default"
+	_, err = jobRepo.Insert(job)
+	require.Nil(t, err)
+
+	return job
+}
+
+func TestJobReaper_Fail(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+	t.Setenv("RABBITMQ_NOTIFICATION_EX", "amq.direct")
+
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+	job := insertStaleJob(t, jobRepo, repository.NewVideoRepository(db))
+	alive := insertStaleJob(t, jobRepo, repository.NewVideoRepository(db))
+	require.Nil(t, jobRepo.Heartbeat(alive.ID))
+
+	workingDir := filepath.Join(os.Getenv("LOCAL_STORAGE_PATH"), job.Video.ID+".renditions")
+	require.Nil(t, os.MkdirAll(workingDir, os.ModePerm))
+
+	publisher := &fakePublisher{}
+	reaper := service.JobReaper{
+		JobRepository: jobRepo,
+		Publisher:     publisher,
+		StaleAfter:    time.Minute,
+		Policy:        service.ReapPolicyFail,
+		MaxAttempts:   3,
+	}
+
+	reaped, err := reaper.Reap()
+	require.Nil(t, err)
+	assert.Equal(t, 1, reaped)
+
+	reapedJob, err := jobRepo.Find(job.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "FAILED", reapedJob.Status)
+	assert.Contains(t, reapedJob.Error, job.WorkerID)
+	assert.NoDirExists(t, workingDir)
+
+	require.Len(t, publisher.Messages, 1)
+	assert.Equal(t, "amq.direct", publisher.Messages[0].Exchange)
+
+	var notification service.JobNotificationError
+	require.Nil(t, json.Unmarshal([]byte(publisher.Messages[0].Body), &notification))
+	assert.Equal(t, service.ErrCodeJobAbandoned, notification.Code)
+
+	reaped, err = reaper.Reap()
+	require.Nil(t, err)
+	assert.Equal(t, 0, reaped)
+}
+
+func TestJobReaper_Requeue(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+	job := insertStaleJob(t, jobRepo, repository.NewVideoRepository(db))
+
+	publisher := &fakePublisher{}
+	reaper := service.JobReaper{
+		JobRepository: jobRepo,
+		Publisher:     publisher,
+		Queue:         service.RabbitMQJobQueue{Publisher: publisher, QueueName: "videos"},
+		StaleAfter:    time.Minute,
+		Policy:        service.ReapPolicyRequeue,
+		MaxAttempts:   3,
+	}
+
+	_, err := reaper.Reap()
+	require.Nil(t, err)
+
+	require.Len(t, publisher.Messages, 2)
+	requeued := publisher.Messages[1]
+	assert.Equal(t, "videos", requeued.RoutingKey)
+
+	var message map[string]any
+	require.Nil(t, json.Unmarshal([]byte(requeued.Body), &message))
+	assert.Equal(t, job.Video.ResourceId, message["resource_id"])
+	assert.Equal(t, "movie.mp4", message["file_path"])
+	assert.Equal(t, "default", message["ladder"])
+	assert.NotContains(t, message, "attempt")
+	assert.Equal(t, map[string]any{service.AttemptHeader: int32(1)}, requeued.Headers)
+}
+
+func TestJobReaper_RequeueStopsAtMaxAttempts(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+	job := insertStaleJob(t, jobRepo, repository.NewVideoRepository(db))
+	job.Options.Attempt = 2
+	_, err := jobRepo.Update(job)
+	require.Nil(t, err)
+
+	publisher := &fakePublisher{}
+	reaper := service.JobReaper{
+		JobRepository: jobRepo,
+		Publisher:     publisher,
+		Queue:         service.RabbitMQJobQueue{Publisher: publisher, QueueName: "videos"},
+		StaleAfter:    time.Minute,
+		Policy:        service.ReapPolicyRequeue,
+		MaxAttempts:   3,
+	}
+
+	_, err = reaper.Reap()
+	require.Nil(t, err)
+
+	assert.Len(t, publisher.Messages, 1)
+}
+
+func TestJobReaper_KeepsWorkingFilesOfOtherProcesses(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+	job := insertStaleJob(t, jobRepo, repository.NewVideoRepository(db))
+	require.Nil(t, db.Model(&domain.Job{}).Where("id = ?", job.ID).UpdateColumn("worker_id", "other-host-1-0").Error)
+
+	workingDir := filepath.Join(os.Getenv("LOCAL_STORAGE_PATH"), job.Video.ID+".renditions")
+	require.Nil(t, os.MkdirAll(workingDir, os.ModePerm))
+
+	reaper := service.JobReaper{
+		JobRepository: jobRepo,
+		Publisher:     &fakePublisher{},
+		StaleAfter:    time.Minute,
+		Policy:        service.ReapPolicyFail,
+		MaxAttempts:   3,
+	}
+
+	reaped, err := reaper.Reap()
+	require.Nil(t, err)
+	assert.Equal(t, 1, reaped)
+	assert.DirExists(t, workingDir)
+}
+
+func TestJobReaper_FailsChunkJobs(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+	job := insertStaleJob(t, jobRepo, repository.NewVideoRepository(db))
+
+	var chunks []*domain.Job
+	for i, status := range []string{"ENCODING", "PENDING", "COMPLETED"} {
+		chunk, err := domain.NewChunkJob(job, i)
+		require.Nil(t, err)
+		chunk.Status = status
+		_, err = jobRepo.Insert(chunk)
+		require.Nil(t, err)
+		chunks = append(chunks, chunk)
+	}
+
+	reaper := service.JobReaper{
+		JobRepository: jobRepo,
+		Publisher:     &fakePublisher{},
+		StaleAfter:    time.Minute,
+		Policy:        service.ReapPolicyFail,
+		MaxAttempts:   3,
+	}
+
+	reaped, err := reaper.Reap()
+	require.Nil(t, err)
+	assert.Equal(t, 1, reaped)
+
+	for i, expected := range []string{"FAILED", "FAILED", "COMPLETED"} {
+		chunk, err := jobRepo.Find(chunks[i].ID)
+		require.Nil(t, err)
+		assert.Equal(t, expected, chunk.Status)
+	}
+}
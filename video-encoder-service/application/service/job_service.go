@@ -3,12 +3,18 @@ package service
 import (
 	"encoder/application/repository"
 	"encoder/domain"
+	"encoder/framework/utils"
 	"errors"
 	"fmt"
+	"log"
 	"os"
+	"slices"
 	"strconv"
+	"time"
 )
 
+const maxJobUpdateAttempts = 3
+
 type JobService struct {
 	Job           *domain.Job
 	JobRepository repository.JobRepository
@@ -16,6 +22,9 @@ type JobService struct {
 }
 
 func (j *JobService) Start() error {
+	j.VideoService.reset()
+	j.VideoService.Options = j.Job.Options
+
 	if err := j.updateJobStatus("UPLOADING"); err != nil {
 		return j.failJob(err)
 	}
@@ -24,6 +33,117 @@ func (j *JobService) Start() error {
 		return j.failJob(err)
 	}
 
+	if err := j.updateJobStatus("PROBING"); err != nil {
+		return j.failJob(err)
+	}
+
+	if err := j.VideoService.Probe(); err != nil {
+		return j.failJob(err)
+	}
+
+	if err := j.updateJobStatus("NORMALIZING"); err != nil {
+		return j.failJob(err)
+	}
+
+	if err := j.VideoService.Normalize(); err != nil {
+		return j.failJob(err)
+	}
+
+	if j.Job.Options.Watermark != nil {
+		// The defaults applied are stored with the job options.
+		watermark := j.Job.Options.Watermark.WithDefaults()
+		err := j.updateJob(func(job *domain.Job) {
+			job.Status = "WATERMARKING"
+			job.Options.Watermark = &watermark
+		})
+		if err != nil {
+			return j.failJob(err)
+		}
+		j.VideoService.Options.Watermark = &watermark
+
+		if err := j.VideoService.ApplyWatermark(os.Getenv("INPUT_BUCKET_NAME")); err != nil {
+			return j.failJob(err)
+		}
+	}
+
+	if j.VideoService.LoudnessRequested() {
+		if err := j.updateJobStatus("NORMALIZING_LOUDNESS"); err != nil {
+			return j.failJob(err)
+		}
+
+		if err := j.VideoService.NormalizeLoudness(); err != nil {
+			return j.failJob(err)
+		}
+	}
+
+	if len(j.Job.Options.Clips) > 0 {
+		if err := j.updateJobStatus("CLIPPING"); err != nil {
+			return j.failJob(err)
+		}
+
+		if err := j.VideoService.GenerateClips(); err != nil {
+			return j.failJob(err)
+		}
+	}
+
+	if len(j.Job.Options.Trim) > 0 {
+		if err := j.updateJobStatus("TRIMMING"); err != nil {
+			return j.failJob(err)
+		}
+
+		if err := j.VideoService.Trim(); err != nil {
+			return j.failJob(err)
+		}
+	}
+
+	if len(j.Job.Options.AudioTracks) > 0 || len(j.Job.Options.AudioLanguages) > 0 {
+		if err := j.updateJobStatus("PREPARING_AUDIO"); err != nil {
+			return j.failJob(err)
+		}
+
+		if err := j.VideoService.PrepareAudioTracks(os.Getenv("INPUT_BUCKET_NAME")); err != nil {
+			return j.failJob(err)
+		}
+	}
+
+	if len(j.Job.Options.Subtitles) > 0 {
+		if err := j.updateJobStatus("PREPARING_SUBTITLES"); err != nil {
+			return j.failJob(err)
+		}
+
+		if err := j.VideoService.PrepareSubtitles(os.Getenv("INPUT_BUCKET_NAME")); err != nil {
+			return j.failJob(err)
+		}
+	}
+
+	if j.Job.Options.Ladder != "" || j.VideoService.ChunkingRequested() {
+		if err := j.updateJobStatus("ANALYZING"); err != nil {
+			return j.failJob(err)
+		}
+
+		ladder, err := j.VideoService.AnalyzeLadder()
+		if err != nil {
+			return j.failJob(err)
+		}
+
+		err = j.updateJob(func(job *domain.Job) {
+			job.Status = "TRANSCODING"
+			job.Ladder = ladder
+		})
+		if err != nil {
+			return j.failJob(err)
+		}
+
+		if j.VideoService.ChunkingRequested() {
+			err = j.transcodeChunked()
+		} else {
+			err = j.VideoService.TranscodeRenditions()
+		}
+		if err != nil {
+			return j.failJob(err)
+		}
+	}
+
 	if err := j.updateJobStatus("FRAGMENTING"); err != nil {
 		return j.failJob(err)
 	}
@@ -32,6 +152,12 @@ func (j *JobService) Start() error {
 		return j.failJob(err)
 	}
 
+	if j.Job.Options.Encryption != nil {
+		if err := j.VideoService.FetchContentKey(); err != nil {
+			return j.failJob(err)
+		}
+	}
+
 	if err := j.updateJobStatus("ENCODING"); err != nil {
 		return j.failJob(err)
 	}
@@ -40,6 +166,81 @@ func (j *JobService) Start() error {
 		return j.failJob(err)
 	}
 
+	if err := j.VideoService.RecordOutputs(); err != nil {
+		return j.failJob(err)
+	}
+
+	if err := j.updateJobStatus("THUMBNAILING"); err != nil {
+		return j.failJob(err)
+	}
+
+	if err := j.VideoService.GenerateThumbnails(); err != nil {
+		return j.failJob(err)
+	}
+
+	if j.Job.Options.Sprites != nil {
+		if err := j.updateJobStatus("GENERATING_SPRITES"); err != nil {
+			return j.failJob(err)
+		}
+
+		if err := j.VideoService.GenerateSprites(); err != nil {
+			return j.failJob(err)
+		}
+	}
+
+	return j.upload()
+}
+
+// RetryUpload re-runs only the uploads that did not land in the output
+// bucket on a previous attempt, reusing the files already encoded locally.
+// This relies on the staged objects kept after a failure, so every file is
+// sent again when UPLOAD_KEEP_STAGING_ON_FAILURE is "false". Only FAILED jobs
+// that got as far as the upload, i.e. with an upload manifest or a packaged
+// output, can be retried; the job is left untouched otherwise.
+func (j *JobService) RetryUpload() error {
+	if j.VideoService.Video == nil {
+		j.VideoService.Video = j.Job.Video
+	}
+
+	if err := j.checkUploadRetry(); err != nil {
+		return err
+	}
+	// CleanUp removes the working files of the requested options.
+	j.VideoService.Options = j.Job.Options
+
+	interval, err := heartbeatInterval()
+	if err != nil {
+		return err
+	}
+
+	if err := j.JobRepository.Heartbeat(j.Job.ID); err != nil {
+		return err
+	}
+	stopHeartbeat := j.keepAlive(interval)
+	defer stopHeartbeat()
+
+	return j.upload()
+}
+
+func (j *JobService) checkUploadRetry() error {
+	if j.Job.Status != "FAILED" {
+		return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("job %v is %v, only failed jobs can retry their upload", j.Job.ID, j.Job.Status))
+	}
+
+	for _, path := range []string{j.uploadManifestPath(), j.VideoService.dashManifestPath()} {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+	}
+
+	return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("job %v has no encoded output to upload", j.Job.ID))
+}
+
+func (j *JobService) uploadManifestPath() string {
+	return fmt.Sprintf("%s/%s.upload.json", os.Getenv("LOCAL_STORAGE_PATH"), j.VideoService.Video.ID)
+}
+
+func (j *JobService) upload() error {
 	if err := j.updateJobStatus("UPLOADING"); err != nil {
 		return j.failJob(err)
 	}
@@ -64,32 +265,95 @@ func (j *JobService) Start() error {
 }
 
 func (j *JobService) performUpload() error {
+	localStoragePath := os.Getenv("LOCAL_STORAGE_PATH")
+
 	videoUpload := NewVideoUpload()
 	videoUpload.OutputBucket = os.Getenv("OUTPUT_BUCKET_NAME")
-	videoUpload.VideoPath = fmt.Sprintf("%s/%s", os.Getenv("LOCAL_STORAGE_PATH"), j.VideoService.Video.ID)
+	videoUpload.VideoPath = fmt.Sprintf("%s/%s", localStoragePath, j.VideoService.Video.ID)
+	videoUpload.ManifestPath = j.uploadManifestPath()
+	videoUpload.KeepStagingOnFailure = os.Getenv("UPLOAD_KEEP_STAGING_ON_FAILURE") != "false"
+	videoUpload.CachePolicy = LoadCachePolicy()
+	videoUpload.Metadata = map[string]string{
+		"job_id":      j.Job.ID,
+		"video_id":    j.VideoService.Video.ID,
+		"resource_id": j.VideoService.Video.ResourceId,
+	}
+
+	if stagingPrefix := os.Getenv("UPLOAD_STAGING_PREFIX"); stagingPrefix != "" {
+		videoUpload.StagingPrefix = stagingPrefix
+	}
 
 	maxConcurrentUploads, err := strconv.Atoi(os.Getenv("MAX_UPLOAD_CONCURRENCY"))
 	if err != nil {
 		return fmt.Errorf("invalid MAX_UPLOAD_CONCURRENCY value: %w", err)
 	}
 
+	videoUpload.MaxRetries, err = utils.EnvInt("UPLOAD_MAX_RETRIES", videoUpload.MaxRetries)
+	if err != nil {
+		return err
+	}
+	if videoUpload.MaxRetries < 0 {
+		return fmt.Errorf("invalid UPLOAD_MAX_RETRIES value: %d is negative", videoUpload.MaxRetries)
+	}
+
+	retryBackoff, err := utils.EnvInt("UPLOAD_RETRY_BACKOFF_MS", int(videoUpload.RetryBackoff/time.Millisecond))
+	if err != nil {
+		return err
+	}
+	videoUpload.RetryBackoff = time.Duration(retryBackoff) * time.Millisecond
+
 	doneUpload := make(chan string)
 	go videoUpload.ProcessUpload(maxConcurrentUploads, doneUpload)
 
 	uploadResult := <-doneUpload
 
 	if uploadResult != "Upload completed" {
-		return j.failJob(errors.New(uploadResult))
+		return errors.New(uploadResult)
 	}
 
 	return nil
 }
 
-func (j *JobService) updateJobStatus(status string) error {
-	var err error
+func heartbeatInterval() (time.Duration, error) {
+	seconds, err := utils.EnvInt("JOB_HEARTBEAT_INTERVAL_SECONDS", 30)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// keepAlive records a heartbeat for the job every interval until the returned
+// function is called, so the reaper can tell the worker is alive.
+func (j *JobService) keepAlive(interval time.Duration) func() {
+	done := make(chan struct{})
+	jobID := j.Job.ID
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
 
-	j.Job.Status = status
-	j.Job, err = j.JobRepository.Update(j.Job)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := j.JobRepository.Heartbeat(jobID); err != nil {
+					log.Printf("error recording heartbeat of job %v: %v", jobID, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+func (j *JobService) updateJobStatus(status string) error {
+	err := j.updateJob(func(job *domain.Job) {
+		job.Status = status
+	})
 
 	if err != nil {
 		return j.failJob(err)
@@ -99,10 +363,10 @@ func (j *JobService) updateJobStatus(status string) error {
 }
 
 func (j *JobService) failJob(error error) error {
-	j.Job.Status = "FAILED"
-	j.Job.Error = error.Error()
-
-	_, err := j.JobRepository.Update(j.Job)
+	err := j.updateJob(func(job *domain.Job) {
+		job.Status = "FAILED"
+		job.Error = error.Error()
+	})
 
 	if err != nil {
 		return err
@@ -111,6 +375,36 @@ func (j *JobService) failJob(error error) error {
 	return error
 }
 
+// updateJob applies a transition to the job and saves it. When the job was
+// modified by another process in the meantime, the latest version is read
+// back and the transition applied again on top of it.
+func (j *JobService) updateJob(transition func(job *domain.Job)) error {
+	for attempt := 1; ; attempt++ {
+		transition(j.Job)
+
+		_, err := j.JobRepository.Update(j.Job)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrConcurrentModification) || attempt == maxJobUpdateAttempts {
+			return err
+		}
+
+		log.Printf("job %v was modified concurrently, retrying update", j.Job.ID)
+
+		latest, err := j.JobRepository.Find(j.Job.ID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(repository.TerminalJobStatuses, latest.Status) {
+			return fmt.Errorf("job %v was already %v by another process", latest.ID, latest.Status)
+		}
+
+		latest.Video = j.Job.Video
+		*j.Job = *latest
+	}
+}
+
 func (v *VideoService) InsertVideo() error {
 	_, err := v.VideoRepository.Insert(v.Video)
 	if err != nil {
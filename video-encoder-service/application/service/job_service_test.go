@@ -0,0 +1,48 @@
+package service_test
+
+import (
+	"encoder/application/repository"
+	"encoder/application/service"
+	"encoder/domain"
+	"encoder/framework/database"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobService_RetryUploadRequiresFailedJobWithOutput(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	db := database.NewDbTest()
+	jobRepo := repository.NewJobRepository(db)
+	videoRepo := repository.NewVideoRepository(db)
+
+	for _, status := range []string{"COMPLETED", "FAILED"} {
+		video := domain.NewVideo()
+		video.ID = uuid.New().String()
+		video.ResourceId = uuid.New().String()
+		video.FilePath = "movie.mp4"
+		_, err := videoRepo.Insert(video)
+		require.Nil(t, err)
+
+		job, err := domain.NewJob("bucket", status, video)
+		require.Nil(t, err)
+		_, err = jobRepo.Insert(job)
+		require.Nil(t, err)
+
+		jobService := service.JobService{
+			Job:           job,
+			JobRepository: jobRepo,
+			VideoService:  service.VideoService{VideoRepository: videoRepo},
+		}
+
+		err = jobService.RetryUpload()
+		assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(err))
+
+		stored, err := jobRepo.Find(job.ID)
+		require.Nil(t, err)
+		assert.Equal(t, status, stored.Status)
+	}
+}
@@ -2,9 +2,10 @@ package service
 
 import (
 	"encoder/domain"
-	"encoder/framework/utils"
-	"encoding/json"
+	"fmt"
+	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,28 +21,29 @@ type JobWorkerResult struct {
 
 var Mutex = &sync.Mutex{}
 
-func JobWorker(messageChannel chan amqp.Delivery, returnChan chan JobWorkerResult, jobService JobService, job domain.Job, workerID int) {
-	for message := range messageChannel {
-		err := utils.IsJson(string(message.Body))
-		if err != nil {
-			returnChan <- returnJobResult(domain.Job{}, &message, err)
-			continue
-		}
+// JobWorker runs one job per message. Every job starts as a copy of
+// template, so nothing set by a previous job carries over to the next one.
+func JobWorker(messageChannel chan amqp.Delivery, returnChan chan JobWorkerResult, jobService JobService, template domain.Job, workerID int) {
+	interval, err := heartbeatInterval()
+	if err != nil {
+		log.Fatalf("Error parsing JOB_HEARTBEAT_INTERVAL_SECONDS: %v", err)
+	}
 
-		err = json.Unmarshal(message.Body, &jobService.VideoService.Video)
+	for message := range messageChannel {
+		video, options, err := parseJobMessage(message.Body)
 		if err != nil {
 			returnChan <- returnJobResult(domain.Job{}, &message, err)
 			continue
 		}
 
-		Mutex.Lock()
-		jobService.VideoService.Video.ID = uuid.New().String()
-		Mutex.Unlock()
-
-		err = jobService.VideoService.Video.Validate()
-		if err != nil {
-			returnChan <- returnJobResult(domain.Job{}, &message, err)
-			continue
+		job := template
+		jobService.VideoService.Video = video
+		options.Attempt = messageAttempt(message)
+		job.Options = options
+		job.Tenant = options.Tenant
+		job.Priority = options.Priority
+		if job.Priority == 0 {
+			job.Priority = int(message.Priority)
 		}
 
 		Mutex.Lock()
@@ -57,6 +59,10 @@ func JobWorker(messageChannel chan amqp.Delivery, returnChan chan JobWorkerResul
 		job.ID = uuid.New().String()
 		job.Status = "STARTING"
 		job.CreatedAt = time.Now()
+		job.Version = 1
+		job.WorkerID = workerName(workerID)
+		now := time.Now()
+		job.HeartbeatAt = &now
 
 		Mutex.Lock()
 		_, err = jobService.JobRepository.Insert(&job)
@@ -68,7 +74,9 @@ func JobWorker(messageChannel chan amqp.Delivery, returnChan chan JobWorkerResul
 
 		jobService.Job = &job
 
+		stopHeartbeat := jobService.keepAlive(interval)
 		err = jobService.Start()
+		stopHeartbeat()
 		if err != nil {
 			returnChan <- returnJobResult(domain.Job{}, &message, err)
 			continue
@@ -78,6 +86,39 @@ func JobWorker(messageChannel chan amqp.Delivery, returnChan chan JobWorkerResul
 	}
 }
 
+// workerName identifies a worker across processes, e.g. "encoder-7f9c-42-0"
+// for worker 0 of process 42 on host encoder-7f9c.
+func workerName(workerID int) string {
+	return fmt.Sprintf("%s-%d", processName(), workerID)
+}
+
+// ownWorker tells whether a worker name belongs to this process.
+func ownWorker(name string) bool {
+	return strings.HasPrefix(name, processName()+"-")
+}
+
+func processName() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// messageAttempt reads the attempt count a requeued message carries in its
+// AttemptHeader.
+func messageAttempt(message amqp.Delivery) int {
+	switch attempt := message.Headers[AttemptHeader].(type) {
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	}
+
+	return 0
+}
+
 func returnJobResult(job domain.Job, message *amqp.Delivery, err error) JobWorkerResult {
 	return JobWorkerResult{
 		Job:     job,
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"encoder/domain"
+	"encoder/framework/utils"
+	"fmt"
+	"log"
+	"math"
+	"os"
+)
+
+const (
+	complexitySamples       = 3
+	complexitySampleSeconds = 4.0
+	// referenceBitrate is what an average 720p sample needs at CRF 23, in kbps.
+	referenceBitrate = 2500.0
+	minComplexity    = 0.5
+	maxComplexity    = 1.6
+)
+
+// AnalyzeLadder picks the bitrate ladder for the job, the default one unless
+// it asked for "per-title". The "per-title" mode
+// runs quick CRF test encodes on a few sampled segments and scales the
+// default ladder by how many bits the content needed compared to an average
+// title.
+func (v *VideoService) AnalyzeLadder() (*domain.EncodingLadder, error) {
+	ladder := &domain.EncodingLadder{
+		Mode:       v.Options.Ladder,
+		Complexity: 1,
+		Default:    domain.DefaultLadder,
+	}
+
+	switch v.Options.Ladder {
+	case "", "default":
+		ladder.Mode = "default"
+	case "per-title":
+		samples, err := v.sampleBitrates()
+		if err != nil {
+			return nil, err
+		}
+		ladder.Complexity = ComplexityScore(samples)
+	default:
+		return nil, NewJobError(ErrCodeInvalidRequest, fmt.Errorf("unknown ladder %q", v.Options.Ladder))
+	}
+
+	ladder.Rungs = DeriveLadder(domain.DefaultLadder, ladder.Complexity, v.Video.MediaInfo.Height)
+	v.Ladder = ladder.Rungs
+
+	log.Printf("video %v ladder: %s, complexity %.2f, %d rungs", v.Video.ID, ladder.Mode, ladder.Complexity, len(ladder.Rungs))
+
+	return ladder, nil
+}
+
+// TranscodeRenditions encodes one video-only MP4 per ladder rung, with key
+// frames aligned every two seconds so the renditions can be switched.
+func (v *VideoService) TranscodeRenditions() error {
+	if err := os.MkdirAll(v.renditionsPath(), os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, rung := range v.Ladder {
+		if err := runFFmpeg(RenditionArgs(rung, v.mp4Path(), v.renditionPath(rung))...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func RenditionArgs(rung domain.LadderRung, source string, target string) []string {
+	return []string{
+		"-y", "-i", source,
+		"-an",
+		"-vf", fmt.Sprintf("scale=-2:%d", rung.Height),
+		"-c:v", "libx264",
+		"-preset", "medium",
+		"-b:v", fmt.Sprintf("%dk", rung.Bitrate),
+		"-maxrate", fmt.Sprintf("%dk", rung.Bitrate*107/100),
+		"-bufsize", fmt.Sprintf("%dk", rung.Bitrate*3/2),
+		"-force_key_frames", "expr:gte(t,n_forced*2)",
+		"-pix_fmt", "yuv420p",
+		"-movflags", "+faststart",
+		target,
+	}
+}
+
+// ComplexityScore compares the sampled CRF bitrates with the reference
+// bitrate, clamped so a single odd sample cannot produce an extreme ladder.
+func ComplexityScore(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 1
+	}
+
+	total := 0.0
+	for _, sample := range samples {
+		total += sample
+	}
+
+	score := total / float64(len(samples)) / referenceBitrate
+
+	return math.Round(math.Min(maxComplexity, math.Max(minComplexity, score))*100) / 100
+}
+
+// DeriveLadder scales the base ladder by the complexity score and drops the
+// rungs above the source height, keeping at least one rendition.
+func DeriveLadder(base []domain.LadderRung, complexity float64, sourceHeight int) []domain.LadderRung {
+	var rungs []domain.LadderRung
+
+	for _, rung := range base {
+		if sourceHeight > 0 && rung.Height > sourceHeight {
+			continue
+		}
+
+		bitrate := int(math.Round(float64(rung.Bitrate)*complexity/50) * 50)
+		rungs = append(rungs, domain.LadderRung{Height: rung.Height, Bitrate: max(bitrate, 200)})
+	}
+
+	if len(rungs) == 0 && len(base) > 0 {
+		lowest := base[len(base)-1]
+		bitrate := int(math.Round(float64(lowest.Bitrate)*complexity/50) * 50)
+		rungs = append(rungs, domain.LadderRung{Height: sourceHeight - sourceHeight%2, Bitrate: max(bitrate, 200)})
+	}
+
+	return rungs
+}
+
+func (v *VideoService) sampleBitrates() ([]float64, error) {
+	duration := v.Video.PackagedDuration()
+	sample := fmt.Sprintf("%s/%s.sample.mp4", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+	defer os.Remove(sample)
+
+	var bitrates []float64
+	for i := 1; i <= complexitySamples; i++ {
+		start := duration * float64(i) / float64(complexitySamples+1)
+		length := math.Min(complexitySampleSeconds, duration-start)
+		if length <= 0 {
+			continue
+		}
+
+		err := runFFmpeg(
+			"-y", "-ss", utils.FormatFloat(start), "-t", utils.FormatFloat(length), "-i", v.mp4Path(),
+			"-an", "-vf", "scale=-2:720",
+			"-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+			sample,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(sample)
+		if err != nil {
+			return nil, err
+		}
+
+		bitrates = append(bitrates, float64(info.Size())*8/1000/length)
+	}
+
+	log.Printf("video %v complexity samples (kbps): %v", v.Video.ID, bitrates)
+
+	return bitrates, nil
+}
+
+func (v *VideoService) renditionsPath() string {
+	return fmt.Sprintf("%s/%s.renditions", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+}
+
+func (v *VideoService) renditionPath(rung domain.LadderRung) string {
+	return fmt.Sprintf("%s/%dp.mp4", v.renditionsPath(), rung.Height)
+}
+
+func (v *VideoService) renditionFragPath(rung domain.LadderRung) string {
+	return fmt.Sprintf("%s/%dp.frag", v.renditionsPath(), rung.Height)
+}
@@ -0,0 +1,102 @@
+package service_test
+
+import (
+	"encoder/application/repository"
+	"encoder/application/service"
+	"encoder/domain"
+	"encoder/framework/database"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplexityScore(t *testing.T) {
+	assert.Equal(t, 1.0, service.ComplexityScore(nil))
+	assert.Equal(t, 0.8, service.ComplexityScore([]float64{1800, 2200}))
+	assert.Equal(t, 0.5, service.ComplexityScore([]float64{100}))
+	assert.Equal(t, 1.6, service.ComplexityScore([]float64{9000}))
+}
+
+func TestDeriveLadder(t *testing.T) {
+	rungs := service.DeriveLadder(domain.DefaultLadder, 0.8, 720)
+
+	assert.Equal(t, []domain.LadderRung{
+		{Height: 720, Bitrate: 2400},
+		{Height: 480, Bitrate: 950},
+		{Height: 360, Bitrate: 550},
+		{Height: 240, Bitrate: 300},
+	}, rungs)
+}
+
+func TestDeriveLadder_SmallSource(t *testing.T) {
+	rungs := service.DeriveLadder(domain.DefaultLadder, 1, 181)
+
+	assert.Equal(t, []domain.LadderRung{{Height: 180, Bitrate: 400}}, rungs)
+}
+
+func TestVideoService_EncodeArgsWithLadder(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", "/tmp/encoder")
+	t.Setenv("OUTPUT_HLS", "")
+
+	videoService := service.NewVideoService()
+	videoService.Video = domain.NewVideo()
+	videoService.Video.ID = "video"
+	videoService.Video.MediaInfo.AudioTracks = 1
+	videoService.Ladder = []domain.LadderRung{{Height: 720, Bitrate: 3000}, {Height: 360, Bitrate: 700}}
+
+	assert.Equal(t, []string{
+		"/tmp/encoder/video.renditions/720p.frag",
+		"/tmp/encoder/video.renditions/360p.frag",
+		"[type=audio]/tmp/encoder/video.frag",
+		"--use-segment-timeline",
+		"-o",
+		"/tmp/encoder/video",
+		"-f",
+	}, videoService.EncodeArgs())
+}
+
+func TestJobService_StartResetsLadder(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+	// Makes the download fail right away, before any encoding step.
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+
+	db := database.NewDbTest()
+	jobService := service.JobService{
+		JobRepository: repository.NewJobRepository(db),
+		VideoService:  service.VideoService{VideoRepository: repository.NewVideoRepository(db)},
+	}
+
+	for _, ladder := range []string{"default", ""} {
+		video := domain.NewVideo()
+		video.ID = uuid.New().String()
+		video.ResourceId = uuid.New().String()
+		video.FilePath = "movie.mp4"
+		video.MediaInfo.Height = 1080
+		_, err := jobService.VideoService.VideoRepository.Insert(video)
+		require.Nil(t, err)
+
+		job, err := domain.NewJob("bucket", "STARTING", video)
+		require.Nil(t, err)
+		job.Options.Ladder = ladder
+		_, err = jobService.JobRepository.Insert(job)
+		require.Nil(t, err)
+
+		jobService.Job = job
+		jobService.VideoService.Video = video
+
+		assert.NotNil(t, jobService.Start())
+
+		if ladder != "" {
+			// The ladder job got as far as the analysis.
+			_, err = jobService.VideoService.AnalyzeLadder()
+			require.Nil(t, err)
+			require.NotEmpty(t, jobService.VideoService.Ladder)
+		}
+	}
+
+	assert.Empty(t, jobService.VideoService.Ladder)
+	assert.Equal(t, []string{"--use-segment-timeline"}, jobService.VideoService.EncodeArgs()[1:2])
+}
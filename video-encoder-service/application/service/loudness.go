@@ -0,0 +1,200 @@
+package service
+
+import (
+	"encoder/domain"
+	"encoder/framework/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// LoudnessMeasurement is the first pass report of ffmpeg's loudnorm filter.
+type LoudnessMeasurement struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+func (m LoudnessMeasurement) Integrated() (float64, error) {
+	return strconv.ParseFloat(m.InputI, 64)
+}
+
+// Valid reports whether every value is a finite number. Silent tracks
+// measure "-inf", which loudnorm can't apply.
+func (m LoudnessMeasurement) Valid() bool {
+	for _, value := range []string{m.InputI, m.InputTP, m.InputLRA, m.InputThresh, m.TargetOffset} {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LoudnessRequested reports whether the job asked for loudness normalization
+// or it is enabled for every job with LOUDNESS_NORMALIZATION.
+func (v *VideoService) LoudnessRequested() bool {
+	return v.Options.Loudness != nil || os.Getenv("LOUDNESS_NORMALIZATION") == "true"
+}
+
+// NormalizeLoudness runs a two-pass EBU R128 normalization on every audio
+// track of the MP4 that is about to be fragmented. The first pass measures
+// each track; the second applies a linear correction towards the target,
+// track by track. Tracks whose measurement isn't finite, e.g. silent ones,
+// are left as they are. The integrated loudness of the first track is stored
+// on the video for reporting, when it was measured.
+func (v *VideoService) NormalizeLoudness() error {
+	if v.Video.MediaInfo.AudioTracks == 0 {
+		log.Printf("video %v has no audio, skipping loudness normalization", v.Video.ID)
+		return nil
+	}
+
+	target, err := v.loudnessTarget()
+	if err != nil {
+		return err
+	}
+
+	measurements := make([]LoudnessMeasurement, v.Video.MediaInfo.AudioTracks)
+	for track := range measurements {
+		output, err := ffmpegOutput(
+			"-hide_banner", "-i", v.mp4Path(),
+			"-map", fmt.Sprintf("0:a:%d", track),
+			"-af", LoudnormFilter(target, nil),
+			"-f", "null", "-",
+		)
+		if err != nil {
+			return err
+		}
+
+		if measurements[track], err = ParseLoudnormOutput(output); err != nil {
+			return err
+		}
+	}
+
+	if !slices.ContainsFunc(measurements, LoudnessMeasurement.Valid) {
+		log.Printf("video %v has no measurable audio, skipping loudness normalization", v.Video.ID)
+		return nil
+	}
+
+	normalized := fmt.Sprintf("%s.loudnorm.mp4", v.mp4Path())
+	if err := runFFmpeg(LoudnormArgs(v.mp4Path(), normalized, target, measurements)...); err != nil {
+		return err
+	}
+
+	if err := os.Rename(normalized, v.mp4Path()); err != nil {
+		return err
+	}
+
+	v.Video.Loudness = nil
+	if measurements[0].Valid() {
+		integrated, _ := measurements[0].Integrated()
+		v.Video.Loudness = &integrated
+	}
+	if _, err := v.VideoRepository.Update(v.Video); err != nil {
+		return err
+	}
+
+	log.Printf("video %v loudness normalized to %.2f LUFS", v.Video.ID, target.TargetLUFS)
+
+	return nil
+}
+
+// LoudnormArgs builds the applying pass: each audio track with a valid
+// measurement gets the filter with its own values, and every track is
+// resampled back to 48 kHz since loudnorm outputs 192 kHz.
+func LoudnormArgs(source string, target string, loudness domain.Loudness, measurements []LoudnessMeasurement) []string {
+	args := []string{
+		"-y", "-i", source,
+		"-map", "0:v:0", "-map", "0:a",
+		"-c:v", "copy",
+	}
+
+	for track := range measurements {
+		if !measurements[track].Valid() {
+			continue
+		}
+		args = append(args, fmt.Sprintf("-filter:a:%d", track), LoudnormFilter(loudness, &measurements[track]))
+	}
+
+	return append(args,
+		"-c:a", "aac", "-b:a", "160k", "-ar", "48000",
+		"-movflags", "+faststart",
+		target,
+	)
+}
+
+func (v *VideoService) loudnessTarget() (domain.Loudness, error) {
+	target := domain.Loudness{TargetLUFS: -23, TruePeak: -1, Range: 7}
+
+	if value := os.Getenv("LOUDNESS_TARGET_LUFS"); value != "" {
+		lufs, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return target, fmt.Errorf("invalid LOUDNESS_TARGET_LUFS value: %w", err)
+		}
+		target.TargetLUFS = lufs
+	}
+
+	if v.Options.Loudness != nil {
+		if v.Options.Loudness.TargetLUFS != 0 {
+			target.TargetLUFS = v.Options.Loudness.TargetLUFS
+		}
+		if v.Options.Loudness.TruePeak != 0 {
+			target.TruePeak = v.Options.Loudness.TruePeak
+		}
+		if v.Options.Loudness.Range != 0 {
+			target.Range = v.Options.Loudness.Range
+		}
+	}
+
+	if target.TargetLUFS < -70 || target.TargetLUFS > -5 {
+		return target, NewJobError(ErrCodeInvalidRequest, fmt.Errorf("target loudness %.1f LUFS out of range", target.TargetLUFS))
+	}
+
+	return target, nil
+}
+
+// LoudnormFilter builds the loudnorm filter for the measuring pass, or for
+// the applying pass when a measurement is given.
+func LoudnormFilter(target domain.Loudness, measurement *LoudnessMeasurement) string {
+	filter := fmt.Sprintf(
+		"loudnorm=I=%s:TP=%s:LRA=%s",
+		utils.FormatFloat(target.TargetLUFS), utils.FormatFloat(target.TruePeak), utils.FormatFloat(target.Range),
+	)
+
+	if measurement == nil {
+		return filter + ":print_format=json"
+	}
+
+	return fmt.Sprintf(
+		"%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		filter, measurement.InputI, measurement.InputTP, measurement.InputLRA, measurement.InputThresh, measurement.TargetOffset,
+	)
+}
+
+// ParseLoudnormOutput extracts the JSON report that loudnorm prints at the end
+// of ffmpeg's log.
+func ParseLoudnormOutput(output []byte) (LoudnessMeasurement, error) {
+	var measurement LoudnessMeasurement
+
+	report := string(output)
+	start := strings.LastIndex(report, "{")
+	end := strings.LastIndex(report, "}")
+	if start == -1 || end < start {
+		return measurement, errors.New("loudnorm report not found in ffmpeg output")
+	}
+
+	if err := json.Unmarshal([]byte(report[start:end+1]), &measurement); err != nil {
+		return measurement, fmt.Errorf("invalid loudnorm report: %w", err)
+	}
+
+	return measurement, nil
+}
@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const loudnormOutput = `[Parsed_loudnorm_0 @ 0x55d8c]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}`
+
+func TestParseLoudnormOutput(t *testing.T) {
+	measurement, err := service.ParseLoudnormOutput([]byte(loudnormOutput))
+
+	require.Nil(t, err)
+	integrated, err := measurement.Integrated()
+	require.Nil(t, err)
+	assert.Equal(t, -27.61, integrated)
+	assert.Equal(t, "0.58", measurement.TargetOffset)
+
+	_, err = service.ParseLoudnormOutput([]byte("no report"))
+	assert.NotNil(t, err)
+}
+
+func TestLoudnormFilter(t *testing.T) {
+	target := domain.Loudness{TargetLUFS: -23, TruePeak: -1, Range: 7}
+
+	assert.Equal(t, "loudnorm=I=-23:TP=-1:LRA=7:print_format=json", service.LoudnormFilter(target, nil))
+
+	measurement, err := service.ParseLoudnormOutput([]byte(loudnormOutput))
+	require.Nil(t, err)
+
+	assert.Equal(
+		t,
+		"loudnorm=I=-23:TP=-1:LRA=7:measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.20:offset=0.58:linear=true",
+		service.LoudnormFilter(target, &measurement),
+	)
+}
+
+func TestLoudnormArgs(t *testing.T) {
+	target := domain.Loudness{TargetLUFS: -23, TruePeak: -1, Range: 7}
+	measurements := []service.LoudnessMeasurement{
+		{InputI: "-27.61", InputTP: "-4.47", InputLRA: "18.06", InputThresh: "-39.20", TargetOffset: "0.58"},
+		{InputI: "-20.00", InputTP: "-2.00", InputLRA: "6.00", InputThresh: "-30.00", TargetOffset: "0.10"},
+	}
+
+	args := service.LoudnormArgs("in.mp4", "out.mp4", target, measurements)
+
+	assert.Contains(t, args, "-filter:a:0")
+	assert.Contains(t, args, service.LoudnormFilter(target, &measurements[0]))
+	assert.Contains(t, args, "-filter:a:1")
+	assert.Contains(t, args, service.LoudnormFilter(target, &measurements[1]))
+	assert.NotContains(t, args, "-af")
+	assert.Equal(t, []string{"-ar", "48000"}, args[len(args)-5:len(args)-3])
+	assert.Equal(t, "out.mp4", args[len(args)-1])
+}
+
+func TestLoudnormArgs_SkipsSilentTracks(t *testing.T) {
+	target := domain.Loudness{TargetLUFS: -23, TruePeak: -1, Range: 7}
+	measurements := []service.LoudnessMeasurement{
+		{InputI: "-inf", InputTP: "-inf", InputLRA: "0.00", InputThresh: "-70.00", TargetOffset: "inf"},
+		{InputI: "-20.00", InputTP: "-2.00", InputLRA: "6.00", InputThresh: "-30.00", TargetOffset: "0.10"},
+	}
+
+	assert.False(t, measurements[0].Valid())
+	assert.True(t, measurements[1].Valid())
+
+	args := service.LoudnormArgs("in.mp4", "out.mp4", target, measurements)
+
+	assert.NotContains(t, args, "-filter:a:0")
+	assert.Contains(t, args, "-filter:a:1")
+	assert.Equal(t, []string{"-map", "0:v:0", "-map", "0:a"}, args[3:7])
+}
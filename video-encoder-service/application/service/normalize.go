@@ -0,0 +1,129 @@
+package service
+
+import (
+	"encoder/domain"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+)
+
+var supportedContainers = map[string]bool{
+	"mp4":  true,
+	"mov":  true,
+	"mkv":  true,
+	"webm": true,
+	"avi":  true,
+}
+
+var mp4VideoCodecs = map[string]bool{
+	"h264": true,
+	"hevc": true,
+	"av1":  true,
+}
+
+var mp4AudioCodecs = map[string]bool{
+	"":     true,
+	"aac":  true,
+	"mp3":  true,
+	"ac3":  true,
+	"eac3": true,
+}
+
+// DetectContainer resolves the source container from the file extension and
+// the format name reported by ffprobe. ffprobe reports the same format for
+// MP4/MOV and for MKV/WebM, so the extension breaks the tie.
+func DetectContainer(ext string, formatName string) string {
+	ext = strings.TrimPrefix(strings.ToLower(ext), ".")
+	formats := strings.Split(formatName, ",")
+
+	switch {
+	case slices.Contains(formats, "mp4") || slices.Contains(formats, "mov"):
+		if ext == "mov" {
+			return "mov"
+		}
+		return "mp4"
+	case slices.Contains(formats, "matroska") || slices.Contains(formats, "webm"):
+		if ext == "webm" {
+			return "webm"
+		}
+		return "mkv"
+	case slices.Contains(formats, "avi"):
+		return "avi"
+	case formatName == "":
+		if ext == "m4v" {
+			return "mp4"
+		}
+		return ext
+	}
+
+	return formats[0]
+}
+
+// NormalizeArgs returns the ffmpeg arguments that turn the source into a
+// packager-compatible MP4, or nil when the source can be fragmented as is.
+// Sources whose codecs already fit in MP4 are remuxed; anything else is
+// transcoded to H.264/AAC.
+func NormalizeArgs(info domain.MediaInfo, source string, target string) []string {
+	codecsFit := mp4VideoCodecs[info.VideoCodec] && mp4AudioCodecs[info.AudioCodec]
+
+	if info.Container == "mp4" && codecsFit {
+		return nil
+	}
+
+	args := []string{"-y", "-i", source, "-map", "0:v:0", "-map", "0:a?"}
+
+	if codecsFit {
+		args = append(args, "-c", "copy")
+		if info.VideoCodec == "hevc" {
+			args = append(args, "-tag:v", "hvc1")
+		}
+	} else {
+		args = append(args,
+			"-c:v", "libx264",
+			"-preset", "medium",
+			"-crf", "20",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "aac",
+			"-b:a", "160k",
+		)
+	}
+
+	return append(args, "-movflags", "+faststart", target)
+}
+
+// Normalize converts MOV, MKV, WebM and AVI sources into an MP4 that
+// mp4fragment accepts. MP4 sources are only rewritten when their codecs do
+// not fit the packager, and moved to the MP4 path when downloaded under
+// another extension, e.g. ".m4v".
+func (v *VideoService) Normalize() error {
+	args := NormalizeArgs(v.Video.MediaInfo, v.sourcePath(), v.mp4Path())
+	if args == nil {
+		if v.sourcePath() == v.mp4Path() {
+			return nil
+		}
+
+		return os.Rename(v.sourcePath(), v.mp4Path())
+	}
+
+	// An MP4 with codecs that need transcoding is moved aside first, since
+	// ffmpeg cannot write over its own input.
+	if v.sourcePath() == v.mp4Path() {
+		original := fmt.Sprintf("%s.orig", v.mp4Path())
+		if err := os.Rename(v.mp4Path(), original); err != nil {
+			return err
+		}
+		defer os.Remove(original)
+
+		args = NormalizeArgs(v.Video.MediaInfo, original, v.mp4Path())
+	}
+
+	if err := runFFmpeg(args...); err != nil {
+		return err
+	}
+
+	log.Printf("video %v normalized from %v", v.Video.ID, v.Video.MediaInfo.Container)
+
+	return nil
+}
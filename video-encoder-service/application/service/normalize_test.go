@@ -0,0 +1,66 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectContainer(t *testing.T) {
+	assert.Equal(t, "mp4", service.DetectContainer(".mp4", "mov,mp4,m4a,3gp,3g2,mj2"))
+	assert.Equal(t, "mov", service.DetectContainer(".MOV", "mov,mp4,m4a,3gp,3g2,mj2"))
+	assert.Equal(t, "mkv", service.DetectContainer(".mkv", "matroska,webm"))
+	assert.Equal(t, "webm", service.DetectContainer(".webm", "matroska,webm"))
+	assert.Equal(t, "avi", service.DetectContainer(".avi", "avi"))
+	assert.Equal(t, "flv", service.DetectContainer(".mp4", "flv"))
+}
+
+func TestNormalizeArgs_Mp4SourceIsKept(t *testing.T) {
+	info := domain.MediaInfo{Container: "mp4", VideoCodec: "h264", AudioCodec: "aac"}
+
+	assert.Nil(t, service.NormalizeArgs(info, "in.mp4", "out.mp4"))
+}
+
+func TestNormalizeArgs_RemuxCompatibleCodecs(t *testing.T) {
+	info := domain.MediaInfo{Container: "mkv", VideoCodec: "h264", AudioCodec: "aac"}
+
+	args := service.NormalizeArgs(info, "in.mkv", "out.mp4")
+
+	assert.Contains(t, args, "copy")
+	assert.NotContains(t, args, "libx264")
+	assert.Equal(t, "out.mp4", args[len(args)-1])
+}
+
+func TestNormalizeArgs_TranscodeIncompatibleCodecs(t *testing.T) {
+	info := domain.MediaInfo{Container: "webm", VideoCodec: "vp9", AudioCodec: "opus"}
+
+	args := service.NormalizeArgs(info, "in.webm", "out.mp4")
+
+	assert.Contains(t, args, "libx264")
+	assert.Contains(t, args, "aac")
+	assert.NotContains(t, args, "copy")
+}
+
+func TestNormalize_MovesMp4SourceWithOtherExtension(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOCAL_STORAGE_PATH", dir)
+
+	video := domain.NewVideo()
+	video.ID = "video"
+	video.FilePath = "movie.m4v"
+	video.MediaInfo = domain.MediaInfo{Container: "mp4", VideoCodec: "h264", AudioCodec: "aac"}
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "video.m4v"), []byte("mp4"), 0644))
+
+	videoService := service.NewVideoService()
+	videoService.Video = video
+
+	require.Nil(t, videoService.Normalize())
+
+	assert.FileExists(t, filepath.Join(dir, "video.mp4"))
+	assert.NoFileExists(t, filepath.Join(dir, "video.m4v"))
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// ObjectStore deletes objects from a bucket, so output removal can be
+// exercised without GCS.
+type ObjectStore interface {
+	DeletePrefix(bucketName string, prefix string) (int, error)
+}
+
+type GCSObjectStore struct{}
+
+// DeletePrefix deletes every object whose name starts with prefix and returns
+// how many were deleted.
+func (GCSObjectStore) DeletePrefix(bucketName string, prefix string) (int, error) {
+	client, ctx, err := getClientUpload()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	bucket := client.Bucket(bucketName)
+	objects := bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+
+	deleted := 0
+	for {
+		attrs, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			return deleted, nil
+		}
+		if err != nil {
+			return deleted, err
+		}
+
+		err = bucket.Object(attrs.Name).Delete(ctx)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return deleted, err
+		}
+		deleted++
+	}
+}
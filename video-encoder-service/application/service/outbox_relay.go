@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoder/application/repository"
+	"encoder/framework/utils"
+	"log"
+	"time"
+)
+
+// OutboxRelay delivers the notifications stored by OutboxPublisher, oldest
+// first, and marks each one published once the publisher accepted it. A
+// notification is sent again if marking it fails, so consumers must accept
+// duplicates.
+type OutboxRelay struct {
+	NotificationRepository repository.NotificationRepository
+	Publisher              Publisher
+	BatchSize              int
+}
+
+// NewOutboxRelay reads the batch size from OUTBOX_RELAY_BATCH_SIZE
+// (default 100).
+func NewOutboxRelay(notificationRepository repository.NotificationRepository, publisher Publisher) (*OutboxRelay, error) {
+	batchSize, err := utils.EnvInt("OUTBOX_RELAY_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxRelay{
+		NotificationRepository: notificationRepository,
+		Publisher:              publisher,
+		BatchSize:              batchSize,
+	}, nil
+}
+
+func (r *OutboxRelay) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := r.Relay(); err != nil {
+			log.Printf("error relaying notifications: %v", err)
+		}
+	}
+}
+
+// Relay publishes the pending notifications and returns how many were
+// delivered. It stops at the first failure so the order is kept.
+func (r *OutboxRelay) Relay() (int, error) {
+	relayed := 0
+
+	for {
+		notifications, err := r.NotificationRepository.ListUnpublished(r.BatchSize)
+		if err != nil {
+			return relayed, err
+		}
+
+		for _, notification := range notifications {
+			err := r.Publisher.Notify(
+				notification.Body,
+				notification.ContentType,
+				notification.Exchange,
+				notification.RoutingKey,
+			)
+			if err != nil {
+				return relayed, err
+			}
+
+			if err := r.NotificationRepository.MarkPublished(notification.ID); err != nil {
+				return relayed, err
+			}
+			relayed++
+		}
+
+		if len(notifications) == 0 || len(notifications) < r.BatchSize {
+			return relayed, nil
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package service_test
+
+import (
+	"encoder/application/repository"
+	"encoder/application/service"
+	"encoder/framework/database"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingPublisher struct{}
+
+func (failingPublisher) Notify(message string, contentType string, exchange string, routingKey string) error {
+	return errors.New("connection closed")
+}
+
+func TestOutboxRelay_Relay(t *testing.T) {
+	db := database.NewDbTest()
+	notificationRepo := repository.NewNotificationRepository(db)
+	outbox := service.OutboxPublisher{NotificationRepository: notificationRepo}
+
+	require.Nil(t, outbox.Notify(`{"status":"COMPLETED"}`, "application/json", "amq.direct", "jobs"))
+	require.Nil(t, outbox.Notify(`{"status":"FAILED"}`, "application/json", "amq.direct", "jobs"))
+
+	failing := service.OutboxRelay{NotificationRepository: notificationRepo, Publisher: failingPublisher{}, BatchSize: 1}
+	_, err := failing.Relay()
+	assert.NotNil(t, err)
+
+	publisher := &fakePublisher{}
+	relay := service.OutboxRelay{NotificationRepository: notificationRepo, Publisher: publisher, BatchSize: 1}
+
+	relayed, err := relay.Relay()
+
+	require.Nil(t, err)
+	assert.Equal(t, 2, relayed)
+	require.Len(t, publisher.Messages, 2)
+	assert.Equal(t, publishedMessage{Body: `{"status":"COMPLETED"}`, Exchange: "amq.direct", RoutingKey: "jobs"}, publisher.Messages[0])
+	assert.Equal(t, `{"status":"FAILED"}`, publisher.Messages[1].Body)
+
+	relayed, err = relay.Relay()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, relayed)
+}
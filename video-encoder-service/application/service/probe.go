@@ -0,0 +1,164 @@
+package service
+
+import (
+	"encoder/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+var supportedVideoCodecs = map[string]bool{
+	"h264":       true,
+	"hevc":       true,
+	"av1":        true,
+	"vp8":        true,
+	"vp9":        true,
+	"mpeg4":      true,
+	"mpeg2video": true,
+	"prores":     true,
+	"msmpeg4v3":  true,
+	"theora":     true,
+}
+
+type probeOutput struct {
+	Streams []struct {
+		CodecName    string            `json:"codec_name"`
+		CodecType    string            `json:"codec_type"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		RFrameRate   string            `json:"r_frame_rate"`
+		Tags         map[string]string `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// Probe inspects the downloaded source with ffprobe, stores what it finds on
+// the video and rejects sources the pipeline cannot encode.
+func (v *VideoService) Probe() error {
+	cmd := exec.Command(
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		v.sourcePath(),
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		// Only a failing ffprobe run blames the source, not being able to
+		// start it is an internal error.
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("error running ffprobe: %w", err)
+		}
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		return NewJobError(ErrCodeInvalidSource, fmt.Errorf("error probing source: %w", err))
+	}
+
+	info, err := ParseProbeOutput(output)
+	if err != nil {
+		return NewJobError(ErrCodeInvalidSource, err)
+	}
+	info.Container = DetectContainer(sourceExt(v.Video.FilePath), info.Container)
+
+	if err := ValidateSource(info); err != nil {
+		return err
+	}
+
+	v.Video.MediaInfo = info
+	if _, err := v.VideoRepository.Update(v.Video); err != nil {
+		return err
+	}
+
+	log.Printf("video %v probed: %s %dx%d %.2fs", v.Video.ID, info.VideoCodec, info.Width, info.Height, info.Duration)
+
+	return nil
+}
+
+func ParseProbeOutput(output []byte) (domain.MediaInfo, error) {
+	var probe probeOutput
+	var info domain.MediaInfo
+
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return info, fmt.Errorf("invalid probe output: %w", err)
+	}
+
+	info.Container = probe.Format.FormatName
+	info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	info.Bitrate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "video":
+			if info.VideoCodec != "" {
+				continue
+			}
+			info.VideoCodec = stream.CodecName
+			info.Width = stream.Width
+			info.Height = stream.Height
+			info.FrameRate = parseFrameRate(stream.AvgFrameRate)
+			if info.FrameRate == 0 {
+				info.FrameRate = parseFrameRate(stream.RFrameRate)
+			}
+		case "audio":
+			if info.AudioCodec == "" {
+				info.AudioCodec = stream.CodecName
+			}
+			info.AudioTracks++
+
+			language := stream.Tags["language"]
+			if language == "" {
+				language = "und"
+			}
+			info.AudioLanguages = append(info.AudioLanguages, language)
+		}
+	}
+
+	return info, nil
+}
+
+func ValidateSource(info domain.MediaInfo) error {
+	switch {
+	case !supportedContainers[info.Container]:
+		return NewJobError(ErrCodeInvalidSource, fmt.Errorf("unsupported container %q", info.Container))
+	case !info.HasVideo():
+		return NewJobError(ErrCodeInvalidSource, errors.New("source has no video stream"))
+	case !supportedVideoCodecs[info.VideoCodec]:
+		return NewJobError(ErrCodeInvalidSource, fmt.Errorf("unsupported video codec %q", info.VideoCodec))
+	case info.Width <= 0 || info.Height <= 0:
+		return NewJobError(ErrCodeInvalidSource, errors.New("source has no valid resolution"))
+	case info.Duration <= 0:
+		return NewJobError(ErrCodeInvalidSource, errors.New("source has no valid duration"))
+	}
+
+	return nil
+}
+
+func parseFrameRate(rate string) float64 {
+	numerator, denominator, found := strings.Cut(rate, "/")
+
+	num, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return num
+	}
+
+	den, err := strconv.ParseFloat(denominator, 64)
+	if err != nil || den == 0 {
+		return 0
+	}
+
+	return num / den
+}
@@ -0,0 +1,69 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const probeJson = `{
+	"streams": [
+		{"codec_name": "h264", "codec_type": "video", "width": 1920, "height": 1080, "avg_frame_rate": "30000/1001"},
+		{"codec_name": "aac", "codec_type": "audio", "tags": {"language": "eng"}},
+		{"codec_name": "aac", "codec_type": "audio"}
+	],
+	"format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "12.500000", "bit_rate": "5000000"}
+}`
+
+func TestParseProbeOutput(t *testing.T) {
+	info, err := service.ParseProbeOutput([]byte(probeJson))
+
+	require.Nil(t, err)
+	assert.Equal(t, "h264", info.VideoCodec)
+	assert.Equal(t, "aac", info.AudioCodec)
+	assert.Equal(t, 1920, info.Width)
+	assert.Equal(t, 1080, info.Height)
+	assert.InDelta(t, 29.97, info.FrameRate, 0.01)
+	assert.Equal(t, 12.5, info.Duration)
+	assert.Equal(t, int64(5000000), info.Bitrate)
+	assert.Equal(t, 2, info.AudioTracks)
+	assert.Equal(t, []string{"eng", "und"}, info.AudioLanguages)
+
+	info.Container = service.DetectContainer(".mp4", info.Container)
+	assert.Nil(t, service.ValidateSource(info))
+}
+
+func TestValidateSource_AudioOnly(t *testing.T) {
+	info := domain.MediaInfo{Container: "mp4", AudioCodec: "aac", Duration: 10, AudioTracks: 1}
+
+	err := service.ValidateSource(info)
+
+	require.NotNil(t, err)
+	assert.Equal(t, service.ErrCodeInvalidSource, service.ErrorCode(err))
+}
+
+func TestValidateSource_UnsupportedCodec(t *testing.T) {
+	info := domain.MediaInfo{Container: "avi", VideoCodec: "mjpeg", Width: 640, Height: 480, Duration: 10}
+
+	err := service.ValidateSource(info)
+
+	require.NotNil(t, err)
+	assert.Equal(t, service.ErrCodeInvalidSource, service.ErrorCode(err))
+}
+
+func TestVideoService_ProbeWithoutFFprobe(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	videoService := service.NewVideoService()
+	videoService.Video = domain.NewVideo()
+	videoService.Video.ID = "video"
+	videoService.Video.FilePath = "movie.mp4"
+
+	err := videoService.Probe()
+	require.NotNil(t, err)
+	assert.Empty(t, service.ErrorCode(err))
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"encoder/domain"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var templateNumber = regexp.MustCompile(`\$Number(%[0-9a-z]+)?\$`)
+
+type mpd struct {
+	Periods []struct {
+		AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+	} `xml:"Period"`
+}
+
+type mpdAdaptationSet struct {
+	MimeType        string              `xml:"mimeType,attr"`
+	ContentType     string              `xml:"contentType,attr"`
+	Lang            string              `xml:"lang,attr"`
+	Codecs          string              `xml:"codecs,attr"`
+	SegmentTemplate *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	Representations []struct {
+		ID              string              `xml:"id,attr"`
+		MimeType        string              `xml:"mimeType,attr"`
+		Codecs          string              `xml:"codecs,attr"`
+		Width           int                 `xml:"width,attr"`
+		Height          int                 `xml:"height,attr"`
+		Bandwidth       int64               `xml:"bandwidth,attr"`
+		SegmentTemplate *mpdSegmentTemplate `xml:"SegmentTemplate"`
+	} `xml:"Representation"`
+}
+
+type mpdSegmentTemplate struct {
+	Initialization string `xml:"initialization,attr"`
+	Media          string `xml:"media,attr"`
+	Timeline       []struct {
+		Repeat int `xml:"r,attr"`
+	} `xml:"SegmentTimeline>S"`
+}
+
+// RecordOutputs reads the manifest written by mp4dash and stores the
+// manifest paths and one rendition per representation on the video.
+func (v *VideoService) RecordOutputs() error {
+	manifest, err := os.ReadFile(v.dashManifestPath())
+	if err != nil {
+		return err
+	}
+
+	renditions, err := ParseMPD(manifest, v.outputPath())
+	if err != nil {
+		return err
+	}
+
+	v.Video.DashManifest = objectName(v.dashManifestPath())
+	v.Video.HlsManifest = ""
+	if _, err := os.Stat(v.hlsManifestPath()); err == nil {
+		v.Video.HlsManifest = objectName(v.hlsManifestPath())
+	}
+
+	for _, rendition := range renditions {
+		rendition.VideoId = v.Video.ID
+	}
+	v.Video.Renditions = renditions
+
+	if err := v.VideoRepository.ReplaceRenditions(v.Video); err != nil {
+		return err
+	}
+
+	log.Printf("%d renditions recorded for video %v", len(renditions), v.Video.ID)
+
+	return nil
+}
+
+// ParseMPD builds the renditions described by a DASH manifest. Sizes are
+// summed from the segment files found under dir, where the manifest lives.
+func ParseMPD(manifest []byte, dir string) ([]*domain.Rendition, error) {
+	var doc mpd
+	if err := xml.Unmarshal(manifest, &doc); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	var renditions []*domain.Rendition
+	for _, period := range doc.Periods {
+		for _, set := range period.AdaptationSets {
+			for _, representation := range set.Representations {
+				template := representation.SegmentTemplate
+				if template == nil {
+					template = set.SegmentTemplate
+				}
+
+				rendition := domain.NewRendition("")
+				rendition.Type = renditionType(set.ContentType, firstNonEmpty(representation.MimeType, set.MimeType))
+				rendition.Codec = firstNonEmpty(representation.Codecs, set.Codecs)
+				rendition.Width = representation.Width
+				rendition.Height = representation.Height
+				rendition.Bitrate = representation.Bandwidth
+				rendition.Language = set.Lang
+
+				if template != nil {
+					init := expandTemplate(template.Initialization, representation.ID, "")
+					media := expandTemplate(template.Media, representation.ID, "*")
+
+					for _, segment := range template.Timeline {
+						rendition.SegmentCount += 1 + segment.Repeat
+					}
+
+					rendition.Path = objectName(filepath.Join(dir, path.Dir(media)))
+					rendition.TotalBytes = filesSize(filepath.Join(dir, init)) + filesSize(filepath.Join(dir, media))
+				}
+
+				renditions = append(renditions, rendition)
+			}
+		}
+	}
+
+	return renditions, nil
+}
+
+func renditionType(contentType string, mimeType string) string {
+	if contentType != "" {
+		return contentType
+	}
+
+	if kind, _, ok := strings.Cut(mimeType, "/"); ok && kind != "application" {
+		return kind
+	}
+
+	return "text"
+}
+
+// expandTemplate fills the $RepresentationID$ and $Number$ identifiers of a
+// segment template, e.g. with "*" to glob every segment.
+func expandTemplate(template string, representationID string, number string) string {
+	if template == "" {
+		return ""
+	}
+
+	template = strings.ReplaceAll(template, "$RepresentationID$", representationID)
+	template = templateNumber.ReplaceAllString(template, number)
+
+	return strings.ReplaceAll(template, "$$", "$")
+}
+
+func filesSize(pattern string) int64 {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0
+	}
+
+	var total int64
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			total += info.Size()
+		}
+	}
+
+	return total
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func (v *VideoService) dashManifestPath() string {
+	return fmt.Sprintf("%s/stream.mpd", v.outputPath())
+}
+
+func (v *VideoService) hlsManifestPath() string {
+	return fmt.Sprintf("%s/master.m3u8", v.outputPath())
+}
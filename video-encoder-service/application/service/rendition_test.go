@@ -0,0 +1,76 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `<?xml version="1.0" ?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+  <Period>
+    <AdaptationSet mimeType="video/mp4" segmentAlignment="true">
+      <SegmentTemplate initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/seg-$Number$.m4s" startNumber="1">
+        <SegmentTimeline>
+          <S t="0" d="4000" r="1"/>
+          <S d="2000"/>
+        </SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="video/avc1/1" codecs="avc1.640028" width="1280" height="720" bandwidth="2500000"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4" lang="en">
+      <Representation id="audio/en/mp4a.40.2" codecs="mp4a.40.2" bandwidth="128000">
+        <SegmentTemplate initialization="audio/en/init.mp4" media="audio/en/seg-$Number%05d$.m4s">
+          <SegmentTimeline>
+            <S t="0" d="4000" r="2"/>
+          </SegmentTimeline>
+        </SegmentTemplate>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+func TestParseMPD(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{
+		"video/avc1/1/init.mp4":  10,
+		"video/avc1/1/seg-1.m4s": 100,
+		"video/avc1/1/seg-2.m4s": 100,
+		"video/avc1/1/seg-3.m4s": 50,
+		"audio/en/init.mp4":      5,
+		"audio/en/seg-00001.m4s": 20,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		require.Nil(t, os.WriteFile(path, make([]byte, size), 0644))
+	}
+
+	renditions, err := service.ParseMPD([]byte(testManifest), dir)
+
+	require.Nil(t, err)
+	require.Len(t, renditions, 2)
+
+	assert.Equal(t, "video", renditions[0].Type)
+	assert.Equal(t, "avc1.640028", renditions[0].Codec)
+	assert.Equal(t, 1280, renditions[0].Width)
+	assert.Equal(t, 720, renditions[0].Height)
+	assert.Equal(t, int64(2500000), renditions[0].Bitrate)
+	assert.Equal(t, 3, renditions[0].SegmentCount)
+	assert.Equal(t, int64(260), renditions[0].TotalBytes)
+
+	assert.Equal(t, "audio", renditions[1].Type)
+	assert.Equal(t, "en", renditions[1].Language)
+	assert.Equal(t, 3, renditions[1].SegmentCount)
+	assert.Equal(t, int64(25), renditions[1].TotalBytes)
+}
+
+func TestParseMPD_Invalid(t *testing.T) {
+	_, err := service.ParseMPD([]byte("not a manifest"), t.TempDir())
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"encoder/application/repository"
+	"encoder/domain"
+	"encoder/framework/utils"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Retention removes encodings: Purge deletes one video on request and Apply
+// hard-deletes the jobs kept longer than MaxAge in one of Statuses, or
+// soft-deleted longer than MaxAge. Chunk jobs are deleted with their parent
+// and the outputs of a video with its last top-level job.
+type Retention struct {
+	JobRepository   repository.JobRepository
+	VideoRepository repository.VideoRepository
+	ObjectStore     ObjectStore
+	OutputBucket    string
+	MaxAge          time.Duration
+	Statuses        []string
+}
+
+// NewRetention reads the policy from RETENTION_DAYS, where 0 disables
+// Apply, and RETENTION_STATUSES, a comma-separated list that defaults to
+// FAILED.
+func NewRetention(jobRepository repository.JobRepository, videoRepository repository.VideoRepository) (*Retention, error) {
+	days, err := utils.EnvInt("RETENTION_DAYS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []string
+	for _, status := range strings.Split(utils.EnvString("RETENTION_STATUSES", "FAILED"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			statuses = append(statuses, status)
+		}
+	}
+
+	return &Retention{
+		JobRepository:   jobRepository,
+		VideoRepository: videoRepository,
+		ObjectStore:     GCSObjectStore{},
+		OutputBucket:    os.Getenv("OUTPUT_BUCKET_NAME"),
+		MaxAge:          time.Duration(days) * 24 * time.Hour,
+		Statuses:        statuses,
+	}, nil
+}
+
+func (r *Retention) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := r.Apply(); err != nil {
+			log.Printf("error applying the retention policy: %v", err)
+		}
+	}
+}
+
+// Purge deletes the outputs and working files of a video, then soft-deletes
+// the video and its jobs. It returns how many objects were deleted.
+func (r *Retention) Purge(videoID string) (int, error) {
+	video, err := r.VideoRepository.Find(videoID)
+	if err != nil {
+		return 0, err
+	}
+
+	buckets := []string{r.OutputBucket}
+	for _, job := range video.Jobs {
+		if job.OutputBucketPath != "" && !slices.Contains(buckets, job.OutputBucketPath) {
+			buckets = append(buckets, job.OutputBucketPath)
+		}
+	}
+
+	deleted, err := r.deleteArtifacts(video.ID, buckets)
+	if err != nil {
+		return deleted, err
+	}
+
+	if err := r.VideoRepository.Delete(video.ID); err != nil {
+		return deleted, err
+	}
+
+	log.Printf("video %v purged, %d objects deleted", video.ID, deleted)
+
+	return deleted, nil
+}
+
+// Apply hard-deletes the jobs past the retention period and returns how many
+// were deleted. A job whose artifacts can't be deleted is kept for the next
+// run.
+func (r *Retention) Apply() (int, error) {
+	if r.MaxAge <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-r.MaxAge)
+	filters := []repository.JobFilter{
+		{DeletedBefore: cutoff, TopLevel: true},
+	}
+	if len(r.Statuses) > 0 {
+		filters = append(filters, repository.JobFilter{Statuses: r.Statuses, UpdatedBefore: cutoff, IncludeDeleted: true, TopLevel: true})
+	}
+
+	deleted := 0
+	for _, filter := range filters {
+		filter.Limit = repository.MaxPageSize
+
+		for {
+			page, err := r.JobRepository.List(filter)
+			if err != nil {
+				return deleted, err
+			}
+
+			for _, job := range page.Items {
+				if err := r.deleteJob(job); err != nil {
+					log.Printf("error deleting job %v: %v", job.ID, err)
+					continue
+				}
+				deleted++
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			filter.Cursor = page.NextCursor
+		}
+	}
+
+	if deleted > 0 {
+		log.Printf("retention policy deleted %d jobs", deleted)
+	}
+
+	return deleted, nil
+}
+
+func (r *Retention) deleteJob(job *domain.Job) error {
+	jobs, err := r.JobRepository.List(repository.JobFilter{VideoId: job.VideoId, TopLevel: true, IncludeDeleted: true, Limit: 2})
+	if err != nil {
+		return err
+	}
+	lastJob := len(jobs.Items) == 1
+
+	if lastJob {
+		if _, err := r.deleteArtifacts(job.VideoId, []string{job.OutputBucketPath}); err != nil {
+			return err
+		}
+	}
+
+	if err := r.deleteChunkJobs(job.ID); err != nil {
+		return err
+	}
+
+	if err := r.JobRepository.HardDelete(job.ID); err != nil {
+		return err
+	}
+
+	if lastJob {
+		return r.VideoRepository.HardDelete(job.VideoId)
+	}
+
+	return nil
+}
+
+func (r *Retention) deleteChunkJobs(parentID string) error {
+	filter := repository.JobFilter{ParentJobId: parentID, IncludeDeleted: true, Limit: repository.MaxPageSize}
+
+	for {
+		page, err := r.JobRepository.List(filter)
+		if err != nil {
+			return err
+		}
+
+		for _, chunk := range page.Items {
+			if err := r.JobRepository.HardDelete(chunk.ID); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// deleteArtifacts deletes the published and staged objects of a video from
+// the buckets, and whatever is left of it in the local storage.
+func (r *Retention) deleteArtifacts(videoID string, buckets []string) (int, error) {
+	stagingPrefix := utils.EnvString("UPLOAD_STAGING_PREFIX", NewVideoUpload().StagingPrefix)
+	prefixes := []string{
+		fmt.Sprintf("%s/", videoID),
+		fmt.Sprintf("%s/%s/", stagingPrefix, videoID),
+	}
+
+	deleted := 0
+	for _, bucket := range buckets {
+		if bucket == "" {
+			continue
+		}
+
+		for _, prefix := range prefixes {
+			count, err := r.ObjectStore.DeletePrefix(bucket, prefix)
+			deleted += count
+			if err != nil {
+				return deleted, fmt.Errorf("error deleting %v from bucket %v: %w", prefix, bucket, err)
+			}
+		}
+	}
+
+	videoService := VideoService{Video: &domain.Video{ID: videoID}}
+	if err := videoService.RemoveWorkingFiles(); err != nil {
+		return deleted, err
+	}
+
+	return deleted, nil
+}
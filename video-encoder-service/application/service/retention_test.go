@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"encoder/application/repository"
+	"encoder/application/service"
+	"encoder/domain"
+	"encoder/framework/database"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fakeObjectStore struct {
+	Deleted []string
+}
+
+func (s *fakeObjectStore) DeletePrefix(bucketName string, prefix string) (int, error) {
+	s.Deleted = append(s.Deleted, bucketName+"/"+prefix)
+	return 1, nil
+}
+
+func newTestRetention(db *gorm.DB) (*service.Retention, *fakeObjectStore) {
+	store := &fakeObjectStore{}
+
+	return &service.Retention{
+		JobRepository:   repository.NewJobRepository(db),
+		VideoRepository: repository.NewVideoRepository(db),
+		ObjectStore:     store,
+		OutputBucket:    "output",
+		MaxAge:          30 * 24 * time.Hour,
+		Statuses:        []string{"FAILED"},
+	}, store
+}
+
+func insertRetentionJob(t *testing.T, db *gorm.DB, video *domain.Video, status string, age time.Duration) *domain.Job {
+	if video == nil {
+		video = domain.NewVideo()
+		video.ID = uuid.New().String()
+		video.ResourceId = uuid.New().String()
+		video.FilePath = "movie.mp4"
+		_, err := repository.NewVideoRepository(db).Insert(video)
+		require.Nil(t, err)
+	}
+
+	job, err := domain.NewJob("output", status, video)
+	require.Nil(t, err)
+	_, err = repository.NewJobRepository(db).Insert(job)
+	require.Nil(t, err)
+
+	require.Nil(t, db.Model(&domain.Job{}).Where("id = ?", job.ID).UpdateColumn("updated_at", time.Now().Add(-age)).Error)
+
+	return job
+}
+
+func TestRetention_Purge(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	db := database.NewDbTest()
+	retention, store := newTestRetention(db)
+	job := insertRetentionJob(t, db, nil, "COMPLETED", time.Hour)
+
+	deleted, err := retention.Purge(job.Video.ID)
+	require.Nil(t, err)
+
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, []string{"output/" + job.Video.ID + "/", "output/staging/" + job.Video.ID + "/"}, store.Deleted)
+
+	_, err = repository.NewVideoRepository(db).Find(job.Video.ID)
+	assert.ErrorIs(t, err, repository.ErrVideoNotFound)
+
+	_, err = retention.Purge(job.Video.ID)
+	assert.ErrorIs(t, err, repository.ErrVideoNotFound)
+}
+
+func TestRetention_Apply(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	db := database.NewDbTest()
+	retention, store := newTestRetention(db)
+	jobRepo := repository.NewJobRepository(db)
+
+	expired := insertRetentionJob(t, db, nil, "FAILED", 40*24*time.Hour)
+	recent := insertRetentionJob(t, db, nil, "FAILED", 24*time.Hour)
+	completed := insertRetentionJob(t, db, nil, "COMPLETED", 40*24*time.Hour)
+	retried := insertRetentionJob(t, db, completed.Video, "FAILED", 40*24*time.Hour)
+
+	deleted, err := retention.Apply()
+	require.Nil(t, err)
+	assert.Equal(t, 2, deleted)
+
+	_, err = jobRepo.Find(expired.ID)
+	assert.ErrorIs(t, err, repository.ErrJobNotFound)
+	_, err = repository.NewVideoRepository(db).Find(expired.Video.ID)
+	assert.ErrorIs(t, err, repository.ErrVideoNotFound)
+	assert.Contains(t, store.Deleted, "output/"+expired.Video.ID+"/")
+
+	_, err = jobRepo.Find(retried.ID)
+	assert.ErrorIs(t, err, repository.ErrJobNotFound)
+	assert.NotContains(t, store.Deleted, "output/"+completed.Video.ID+"/")
+
+	_, err = jobRepo.Find(recent.ID)
+	assert.Nil(t, err)
+	_, err = jobRepo.Find(completed.ID)
+	assert.Nil(t, err)
+}
+
+func TestRetention_ApplyDeletesChunkJobsWithParent(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", t.TempDir())
+
+	db := database.NewDbTest()
+	retention, store := newTestRetention(db)
+	jobRepo := repository.NewJobRepository(db)
+
+	parent := insertRetentionJob(t, db, nil, "FAILED", 40*24*time.Hour)
+	var chunks []*domain.Job
+	for i := 0; i < 2; i++ {
+		chunk, err := domain.NewChunkJob(parent, i)
+		require.Nil(t, err)
+		_, err = jobRepo.Insert(chunk)
+		require.Nil(t, err)
+		chunks = append(chunks, chunk)
+	}
+
+	deleted, err := retention.Apply()
+	require.Nil(t, err)
+	assert.Equal(t, 1, deleted)
+
+	_, err = jobRepo.Find(parent.ID)
+	assert.ErrorIs(t, err, repository.ErrJobNotFound)
+	for _, chunk := range chunks {
+		_, err = jobRepo.Find(chunk.ID)
+		assert.ErrorIs(t, err, repository.ErrJobNotFound)
+	}
+
+	_, err = repository.NewVideoRepository(db).Find(parent.Video.ID)
+	assert.ErrorIs(t, err, repository.ErrVideoNotFound)
+	assert.Contains(t, store.Deleted, "output/"+parent.Video.ID+"/")
+}
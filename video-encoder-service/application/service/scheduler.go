@@ -0,0 +1,147 @@
+package service
+
+import "encoding/json"
+
+type scheduledItem[T any] struct {
+	tenant   string
+	priority int
+	seq      int
+	value    T
+}
+
+// FairScheduler orders pending work by priority while capping how many items
+// of the same tenant run at once. Among equal priorities the tenant served
+// least recently goes first, so one tenant's backlog does not starve the
+// others. Items without a tenant are never capped.
+//
+// MaxPending bounds how many items the scheduler holds, and a capped tenant
+// may not hold more than MaxPerTenant of them, so one tenant's backlog can't
+// fill it. Zero means no bound.
+type FairScheduler[T any] struct {
+	MaxPerTenant int
+	MaxPending   int
+	pending      []scheduledItem[T]
+	running      map[string]int
+	lastServed   map[string]int
+	seq          int
+}
+
+func NewFairScheduler[T any](maxPerTenant int) *FairScheduler[T] {
+	return &FairScheduler[T]{
+		MaxPerTenant: maxPerTenant,
+		running:      map[string]int{},
+		lastServed:   map[string]int{},
+	}
+}
+
+func (s *FairScheduler[T]) Push(tenant string, priority int, value T) {
+	s.seq++
+	s.pending = append(s.pending, scheduledItem[T]{tenant: tenant, priority: priority, seq: s.seq, value: value})
+}
+
+// Peek returns the item Pop would return, if any may run now.
+func (s *FairScheduler[T]) Peek() (T, bool) {
+	index := s.next()
+	if index < 0 {
+		var zero T
+		return zero, false
+	}
+
+	return s.pending[index].value, true
+}
+
+// Pop removes the next item that may run and counts it as running for its
+// tenant until Done is called.
+func (s *FairScheduler[T]) Pop() (T, bool) {
+	index := s.next()
+	if index < 0 {
+		var zero T
+		return zero, false
+	}
+
+	item := s.pending[index]
+	s.pending = append(s.pending[:index], s.pending[index+1:]...)
+
+	s.seq++
+	s.running[item.tenant]++
+	s.lastServed[item.tenant] = s.seq
+
+	return item.value, true
+}
+
+func (s *FairScheduler[T]) Done(tenant string) {
+	if s.running[tenant] > 0 {
+		s.running[tenant]--
+	}
+}
+
+func (s *FairScheduler[T]) Len() int {
+	return len(s.pending)
+}
+
+// Full tells whether the scheduler holds MaxPending items.
+func (s *FairScheduler[T]) Full() bool {
+	return s.MaxPending > 0 && len(s.pending) >= s.MaxPending
+}
+
+// Accepts tells whether an item of tenant may be pushed, i.e. the tenant
+// does not already hold its share of the pending items.
+func (s *FairScheduler[T]) Accepts(tenant string) bool {
+	if tenant == "" || s.MaxPerTenant <= 0 {
+		return true
+	}
+
+	waiting := 0
+	for _, item := range s.pending {
+		if item.tenant == tenant {
+			waiting++
+		}
+	}
+
+	return waiting < s.MaxPerTenant
+}
+
+func (s *FairScheduler[T]) next() int {
+	best := -1
+	for i, item := range s.pending {
+		if !s.mayRun(item.tenant) {
+			continue
+		}
+		if best < 0 || s.before(item, s.pending[best]) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+func (s *FairScheduler[T]) mayRun(tenant string) bool {
+	return tenant == "" || s.MaxPerTenant <= 0 || s.running[tenant] < s.MaxPerTenant
+}
+
+func (s *FairScheduler[T]) before(a scheduledItem[T], b scheduledItem[T]) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if a.tenant != b.tenant && s.lastServed[a.tenant] != s.lastServed[b.tenant] {
+		return s.lastServed[a.tenant] < s.lastServed[b.tenant]
+	}
+
+	return a.seq < b.seq
+}
+
+// messageScheduling reads the tenant and priority of an encode message.
+// Messages that can't be parsed get the defaults and fail in the worker.
+func messageScheduling(message []byte, fallbackPriority int) (string, int) {
+	var options struct {
+		Tenant   string `json:"tenant"`
+		Priority int    `json:"priority"`
+	}
+	json.Unmarshal(message, &options)
+
+	if options.Priority == 0 {
+		options.Priority = fallbackPriority
+	}
+
+	return options.Tenant, options.Priority
+}
@@ -0,0 +1,90 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func popAll(scheduler *service.FairScheduler[string]) []string {
+	var order []string
+	for {
+		value, ok := scheduler.Pop()
+		if !ok {
+			return order
+		}
+		order = append(order, value)
+	}
+}
+
+func TestFairScheduler_Priority(t *testing.T) {
+	scheduler := service.NewFairScheduler[string](0)
+	scheduler.Push("", 0, "low")
+	scheduler.Push("", 5, "high")
+	scheduler.Push("", 0, "low-2")
+
+	assert.Equal(t, []string{"high", "low", "low-2"}, popAll(scheduler))
+}
+
+func TestFairScheduler_RoundRobinsTenants(t *testing.T) {
+	scheduler := service.NewFairScheduler[string](0)
+	scheduler.Push("bulk", 0, "bulk-1")
+	scheduler.Push("bulk", 0, "bulk-2")
+	scheduler.Push("bulk", 0, "bulk-3")
+	scheduler.Push("other", 0, "other-1")
+
+	assert.Equal(t, []string{"bulk-1", "other-1", "bulk-2", "bulk-3"}, popAll(scheduler))
+}
+
+func TestFairScheduler_CapsTenants(t *testing.T) {
+	scheduler := service.NewFairScheduler[string](1)
+	scheduler.Push("bulk", 0, "bulk-1")
+	scheduler.Push("bulk", 0, "bulk-2")
+	scheduler.Push("", 0, "untenanted")
+
+	assert.Equal(t, []string{"bulk-1", "untenanted"}, popAll(scheduler))
+	assert.Equal(t, 1, scheduler.Len())
+
+	_, ok := scheduler.Peek()
+	assert.False(t, ok)
+
+	scheduler.Done("bulk")
+
+	next, ok := scheduler.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, "bulk-2", next)
+}
+
+func TestFairScheduler_BoundsPendingItems(t *testing.T) {
+	scheduler := service.NewFairScheduler[string](1)
+	scheduler.MaxPending = 3
+
+	assert.True(t, scheduler.Accepts("bulk"))
+	scheduler.Push("bulk", 0, "bulk-1")
+	assert.False(t, scheduler.Accepts("bulk"))
+	assert.True(t, scheduler.Accepts("other"))
+	assert.True(t, scheduler.Accepts(""))
+
+	scheduler.Push("other", 0, "other-1")
+	assert.False(t, scheduler.Full())
+	scheduler.Push("", 0, "untenanted-1")
+	assert.True(t, scheduler.Full())
+
+	scheduler.Pop()
+	assert.False(t, scheduler.Full())
+}
+
+func TestDispatchPrefetch(t *testing.T) {
+	t.Setenv("MAX_CONVERSION_CONCURRENCY", "3")
+
+	prefetch, err := service.DispatchPrefetch()
+	assert.Nil(t, err)
+	assert.Equal(t, 9, prefetch)
+
+	t.Setenv("JOB_SCHEDULER_MAX_PENDING", "10")
+
+	prefetch, err = service.DispatchPrefetch()
+	assert.Nil(t, err)
+	assert.Equal(t, 13, prefetch)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"encoder/domain"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+)
+
+// GenerateSprites samples a frame every few seconds, tiles the frames into
+// sprite images and writes the WebVTT track players use for seek bar
+// previews.
+func (v *VideoService) GenerateSprites() error {
+	options := v.Options.Sprites.WithDefaults()
+
+	folder := fmt.Sprintf("%s/sprites", v.outputPath())
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+		return err
+	}
+
+	filter := fmt.Sprintf(
+		"fps=1/%d,scale=%d:%d,tile=%dx%d",
+		options.Interval, options.Width, options.Height, options.Columns, options.Rows,
+	)
+
+	err := runFFmpeg("-y", "-i", v.mp4Path(), "-vf", filter, "-q:v", "3", fmt.Sprintf("%s/sprite_%%03d.jpg", folder))
+	if err != nil {
+		return err
+	}
+
+	track := fmt.Sprintf("%s/sprites.vtt", folder)
+	vtt := BuildSpriteVTT(options, v.Video.PackagedDuration())
+	if err := os.WriteFile(track, []byte(vtt), 0644); err != nil {
+		return err
+	}
+
+	v.Video.SpriteTrack = objectName(track)
+	if _, err := v.VideoRepository.Update(v.Video); err != nil {
+		return err
+	}
+
+	log.Printf("sprites generated for video %v", v.Video.ID)
+
+	return nil
+}
+
+// BuildSpriteVTT maps every sampled interval to its tile inside the sprite
+// images produced by ffmpeg (sprite_001.jpg, sprite_002.jpg, ...).
+func BuildSpriteVTT(options domain.SpriteOptions, duration float64) string {
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+
+	perSprite := options.Columns * options.Rows
+	frames := int(math.Ceil(duration / float64(options.Interval)))
+
+	for frame := 0; frame < frames; frame++ {
+		start := float64(frame * options.Interval)
+		end := math.Min(start+float64(options.Interval), duration)
+
+		tile := frame % perSprite
+		x := (tile % options.Columns) * options.Width
+		y := (tile / options.Columns) * options.Height
+
+		fmt.Fprintf(
+			&vtt,
+			"\n%s --> %s\nsprite_%03d.jpg#xywh=%d,%d,%d,%d\n",
+			formatVTTTimestamp(start), formatVTTTimestamp(end),
+			frame/perSprite+1, x, y, options.Width, options.Height,
+		)
+	}
+
+	return vtt.String()
+}
+
+func formatVTTTimestamp(seconds float64) string {
+	millis := int64(math.Round(seconds * 1000))
+
+	return fmt.Sprintf(
+		"%02d:%02d:%02d.%03d",
+		millis/3600000, millis/60000%60, millis/1000%60, millis%1000,
+	)
+}
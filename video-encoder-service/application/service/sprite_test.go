@@ -0,0 +1,32 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSpriteVTT(t *testing.T) {
+	options := domain.SpriteOptions{Interval: 10, Width: 160, Height: 90, Columns: 2, Rows: 2}
+
+	vtt := service.BuildSpriteVTT(options, 45)
+
+	expected := "WEBVTT\n" +
+		"\n00:00:00.000 --> 00:00:10.000\nsprite_001.jpg#xywh=0,0,160,90\n" +
+		"\n00:00:10.000 --> 00:00:20.000\nsprite_001.jpg#xywh=160,0,160,90\n" +
+		"\n00:00:20.000 --> 00:00:30.000\nsprite_001.jpg#xywh=0,90,160,90\n" +
+		"\n00:00:30.000 --> 00:00:40.000\nsprite_001.jpg#xywh=160,90,160,90\n" +
+		"\n00:00:40.000 --> 00:00:45.000\nsprite_002.jpg#xywh=0,0,160,90\n"
+
+	assert.Equal(t, expected, vtt)
+}
+
+func TestSpriteOptions_WithDefaults(t *testing.T) {
+	options := domain.SpriteOptions{Interval: 5}.WithDefaults()
+
+	assert.Equal(t, 5, options.Interval)
+	assert.Equal(t, 160, options.Width)
+	assert.Equal(t, 10, options.Columns)
+}
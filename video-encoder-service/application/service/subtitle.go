@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoder/domain"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	languageCode = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	srtTiming    = regexp.MustCompile(`(\d{2}:\d{2}:\d{2}),(\d{3})`)
+)
+
+// PrepareSubtitles downloads the requested subtitle files from the input
+// bucket and stores them as WebVTT, converting SRT files on the way.
+func (v *VideoService) PrepareSubtitles(bucketName string) error {
+	if err := ValidateSubtitles(v.Options.Subtitles); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(v.subtitlesPath(), os.ModePerm); err != nil {
+		return err
+	}
+
+	for i, subtitle := range v.Options.Subtitles {
+		target := v.subtitlePath(i, subtitle)
+		download := target
+		if subtitleFormat(subtitle.FilePath) == "srt" {
+			download = strings.TrimSuffix(target, ".vtt") + ".srt"
+		}
+
+		if err := downloadObject(bucketName, subtitle.FilePath, download); err != nil {
+			return fmt.Errorf("error downloading subtitle %v: %w", subtitle.FilePath, err)
+		}
+
+		if download == target {
+			continue
+		}
+
+		srt, err := os.ReadFile(download)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(target, []byte(ConvertSRTToVTT(string(srt))), 0644); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("%d subtitles prepared for video %v", len(v.Options.Subtitles), v.Video.ID)
+
+	return nil
+}
+
+func ValidateSubtitles(subtitles []domain.SubtitleTrack) error {
+	for _, subtitle := range subtitles {
+		if subtitle.FilePath == "" {
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("subtitle file_path can't be empty"))
+		}
+		if !languageCode.MatchString(subtitle.Language) {
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("invalid subtitle language %q", subtitle.Language))
+		}
+		if subtitleFormat(subtitle.FilePath) == "" {
+			return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("unsupported subtitle format %q", subtitle.FilePath))
+		}
+	}
+
+	return nil
+}
+
+// ConvertSRTToVTT rewrites an SRT document as WebVTT: it adds the header and
+// swaps the comma in cue timings for a dot. Cue numbers are valid WebVTT
+// identifiers and are kept.
+func ConvertSRTToVTT(srt string) string {
+	srt = strings.TrimPrefix(srt, "\ufeff")
+	srt = strings.ReplaceAll(srt, "\r\n", "\n")
+
+	lines := strings.Split(strings.TrimSpace(srt), "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "-->") {
+			lines[i] = srtTiming.ReplaceAllString(line, "$1.$2")
+		}
+	}
+
+	return "WEBVTT\n\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// SubtitleInput builds the mp4dash input that packages a WebVTT file as a
+// text track with its language.
+func SubtitleInput(subtitle domain.SubtitleTrack, path string) string {
+	spec := []string{"+format=webvtt", "+language=" + subtitle.Language}
+	if subtitle.Label != "" {
+		spec = append(spec, "+language_name="+subtitle.Label)
+	}
+
+	return fmt.Sprintf("[%s]%s", strings.Join(spec, ","), path)
+}
+
+func subtitleFormat(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".srt":
+		return "srt"
+	case ".vtt", ".webvtt":
+		return "vtt"
+	}
+
+	return ""
+}
+
+func (v *VideoService) subtitlesPath() string {
+	return fmt.Sprintf("%s/%s.subtitles", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+}
+
+func (v *VideoService) subtitlePath(index int, subtitle domain.SubtitleTrack) string {
+	return fmt.Sprintf("%s/%d_%s.vtt", v.subtitlesPath(), index, subtitle.Language)
+}
@@ -0,0 +1,36 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertSRTToVTT(t *testing.T) {
+	srt := "\ufeff1\r\n00:00:01,000 --> 00:00:02,500\r\nHello, world\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nBye\r\n"
+
+	expected := "WEBVTT\n\n" +
+		"1\n00:00:01.000 --> 00:00:02.500\nHello, world\n\n" +
+		"2\n00:00:03.000 --> 00:00:04.000\nBye\n"
+
+	assert.Equal(t, expected, service.ConvertSRTToVTT(srt))
+}
+
+func TestSubtitleInput(t *testing.T) {
+	subtitle := domain.SubtitleTrack{FilePath: "subs/en.srt", Language: "en", Label: "English"}
+
+	assert.Equal(t, "[+format=webvtt,+language=en,+language_name=English]/tmp/0_en.vtt", service.SubtitleInput(subtitle, "/tmp/0_en.vtt"))
+}
+
+func TestValidateSubtitles(t *testing.T) {
+	valid := []domain.SubtitleTrack{{FilePath: "subs/pt.vtt", Language: "pt-BR"}}
+	assert.Nil(t, service.ValidateSubtitles(valid))
+
+	invalidLanguage := []domain.SubtitleTrack{{FilePath: "subs/pt.vtt", Language: "portuguese!"}}
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateSubtitles(invalidLanguage)))
+
+	invalidFormat := []domain.SubtitleTrack{{FilePath: "subs/pt.ass", Language: "pt"}}
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateSubtitles(invalidFormat)))
+}
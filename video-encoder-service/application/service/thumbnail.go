@@ -0,0 +1,120 @@
+package service
+
+import (
+	"encoder/framework/utils"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type ThumbnailSize struct {
+	Width  int
+	Height int
+}
+
+func (s ThumbnailSize) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// ParseThumbnailSizes parses a comma separated list of sizes such as
+// "1280x720,640x360".
+func ParseThumbnailSizes(value string) ([]ThumbnailSize, error) {
+	var sizes []ThumbnailSize
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		w, h, found := strings.Cut(item, "x")
+		width, errW := strconv.Atoi(w)
+		height, errH := strconv.Atoi(h)
+		if !found || errW != nil || errH != nil || width <= 0 || height <= 0 {
+			return nil, fmt.Errorf("invalid thumbnail size %q", item)
+		}
+
+		sizes = append(sizes, ThumbnailSize{Width: width, Height: height})
+	}
+
+	return sizes, nil
+}
+
+// ThumbnailTimestamp resolves the poster frame position. "auto" (or an empty
+// setting) asks for a scene-detected frame; a number is a position in
+// seconds, clamped to the middle of the video when it is past the end.
+func ThumbnailTimestamp(setting string, duration float64) (float64, bool, error) {
+	if setting == "" || setting == "auto" {
+		return duration * 0.1, true, nil
+	}
+
+	timestamp, err := strconv.ParseFloat(setting, 64)
+	if err != nil || timestamp < 0 {
+		return 0, false, fmt.Errorf("invalid THUMBNAIL_TIMESTAMP value %q", setting)
+	}
+
+	if duration > 0 && timestamp >= duration {
+		timestamp = duration / 2
+	}
+
+	return timestamp, false, nil
+}
+
+// GenerateThumbnails extracts a poster frame next to the encoded manifest and
+// scales it to every configured size. The object paths are stored on the
+// video so they are sent with the success notification.
+func (v *VideoService) GenerateThumbnails() error {
+	sizes, err := ParseThumbnailSizes(utils.EnvString("THUMBNAIL_SIZES", "1280x720,640x360,320x180"))
+	if err != nil {
+		return err
+	}
+
+	timestamp, auto, err := ThumbnailTimestamp(os.Getenv("THUMBNAIL_TIMESTAMP"), v.Video.PackagedDuration())
+	if err != nil {
+		return err
+	}
+
+	folder := fmt.Sprintf("%s/thumbnails", v.outputPath())
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+		return err
+	}
+
+	poster := fmt.Sprintf("%s/poster.jpg", folder)
+	args := []string{"-y", "-ss", strconv.FormatFloat(timestamp, 'f', 3, 64), "-i", v.mp4Path()}
+	if auto {
+		// the thumbnail filter picks the most representative frame of the batch
+		args = append(args, "-vf", "thumbnail=300")
+	}
+	args = append(args, "-frames:v", "1", "-q:v", "2", poster)
+
+	if err := runFFmpeg(args...); err != nil {
+		return err
+	}
+
+	thumbnails := []string{objectName(poster)}
+
+	for _, size := range sizes {
+		target := fmt.Sprintf("%s/poster_%s.jpg", folder, size)
+		scale := fmt.Sprintf(
+			"scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2",
+			size.Width, size.Height, size.Width, size.Height,
+		)
+
+		if err := runFFmpeg("-y", "-i", poster, "-vf", scale, "-q:v", "2", target); err != nil {
+			return err
+		}
+
+		thumbnails = append(thumbnails, objectName(target))
+	}
+
+	v.Video.Thumbnails = thumbnails
+	if _, err := v.VideoRepository.Update(v.Video); err != nil {
+		return err
+	}
+
+	log.Printf("%d thumbnails generated for video %v", len(thumbnails), v.Video.ID)
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThumbnailSizes(t *testing.T) {
+	sizes, err := service.ParseThumbnailSizes("1280x720, 320x180")
+
+	require.Nil(t, err)
+	assert.Equal(t, []service.ThumbnailSize{{Width: 1280, Height: 720}, {Width: 320, Height: 180}}, sizes)
+
+	_, err = service.ParseThumbnailSizes("1280")
+	assert.NotNil(t, err)
+}
+
+func TestThumbnailTimestamp(t *testing.T) {
+	timestamp, auto, err := service.ThumbnailTimestamp("auto", 100)
+	require.Nil(t, err)
+	assert.True(t, auto)
+	assert.Equal(t, 10.0, timestamp)
+
+	timestamp, auto, err = service.ThumbnailTimestamp("42.5", 100)
+	require.Nil(t, err)
+	assert.False(t, auto)
+	assert.Equal(t, 42.5, timestamp)
+
+	timestamp, _, err = service.ThumbnailTimestamp("500", 100)
+	require.Nil(t, err)
+	assert.Equal(t, 50.0, timestamp)
+
+	_, _, err = service.ThumbnailTimestamp("soon", 100)
+	assert.NotNil(t, err)
+}
@@ -7,35 +7,54 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
 )
 
 type VideoUpload struct {
-	Paths        []string
-	VideoPath    string
-	OutputBucket string
-	Errors       []string
+	Paths                []string
+	VideoPath            string
+	OutputBucket         string
+	Errors               []string
+	MaxRetries           int
+	RetryBackoff         time.Duration
+	ManifestPath         string
+	Manifest             *UploadManifest
+	StagingPrefix        string
+	KeepStagingOnFailure bool
+	CachePolicy          CachePolicy
+	Metadata             map[string]string
+	mutex                sync.Mutex
 }
 
 func NewVideoUpload() *VideoUpload {
-	return &VideoUpload{}
+	return &VideoUpload{
+		MaxRetries:           3,
+		RetryBackoff:         500 * time.Millisecond,
+		Manifest:             NewUploadManifest(),
+		StagingPrefix:        "staging",
+		KeepStagingOnFailure: true,
+		CachePolicy:          NewCachePolicy(),
+		Metadata:             map[string]string{},
+	}
 }
 
 func (vu *VideoUpload) UploadObject(objectPath string, client *storage.Client, ctx context.Context) error {
-	paths := strings.Split(objectPath, fmt.Sprintf("%s/", os.Getenv("LOCAL_STORAGE_PATH")))
-
 	f, err := os.Open(objectPath)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	wc := client.Bucket(vu.OutputBucket).Object(paths[1]).NewWriter(ctx)
+	wc := client.Bucket(vu.OutputBucket).Object(vu.stagedName(objectName(objectPath))).NewWriter(ctx)
+	vu.setObjectAttrs(&wc.ObjectAttrs, objectPath)
 
 	if _, err = io.Copy(wc, f); err != nil {
+		wc.Close()
 		return err
 	}
 
@@ -63,9 +82,38 @@ func (vu *VideoUpload) loadPaths() error {
 	return nil
 }
 
+// PendingPaths returns the local paths whose objects are not yet recorded as
+// uploaded in the manifest.
+func (vu *VideoUpload) PendingPaths() []string {
+	var pending []string
+	for _, path := range vu.Paths {
+		if !vu.Manifest.IsUploaded(objectName(path)) {
+			pending = append(pending, path)
+		}
+	}
+
+	return pending
+}
+
 func (vu *VideoUpload) ProcessUpload(maxConcurrentUploads int, doneUpload chan string) error {
-	in := make(chan int, runtime.NumCPU())
-	returnChannel := make(chan string)
+	err := vu.processUpload(maxConcurrentUploads)
+	if err != nil {
+		doneUpload <- err.Error()
+		return err
+	}
+
+	doneUpload <- "Upload completed"
+	return nil
+}
+
+func (vu *VideoUpload) processUpload(maxConcurrentUploads int) error {
+	if vu.ManifestPath != "" {
+		manifest, err := LoadUploadManifest(vu.ManifestPath)
+		if err != nil {
+			return err
+		}
+		vu.Manifest = manifest
+	}
 
 	if err := vu.loadPaths(); err != nil {
 		return err
@@ -75,43 +123,204 @@ func (vu *VideoUpload) ProcessUpload(maxConcurrentUploads int, doneUpload chan s
 	if err != nil {
 		return err
 	}
+	defer client.Close()
+
+	media, manifests := splitManifests(vu.PendingPaths())
+
+	// Manifests only go up once every segment they reference has landed, so
+	// a player never sees a manifest pointing at missing media.
+	err = vu.uploadBatch(media, maxConcurrentUploads, client, ctx)
+	if err == nil {
+		err = vu.uploadBatch(manifests, maxConcurrentUploads, client, ctx)
+	}
+	if err == nil {
+		err = vu.promote(client, ctx)
+	}
 
+	// The staged objects and the manifest are kept on failure so a retry only
+	// sends what did not land, unless KeepStagingOnFailure is turned off.
+	if err != nil && vu.StagingPrefix != "" && !vu.KeepStagingOnFailure {
+		vu.discardStaging(client, ctx)
+	}
+
+	if vu.ManifestPath != "" {
+		if saveErr := vu.Manifest.Save(vu.ManifestPath); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+
+	return err
+}
+
+func (vu *VideoUpload) uploadBatch(paths []string, maxConcurrentUploads int, client *storage.Client, ctx context.Context) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	in := make(chan string, len(paths))
+	for _, path := range paths {
+		in <- path
+	}
+	close(in)
+
+	returnChannel := make(chan string)
 	for process := 0; process < maxConcurrentUploads; process++ {
 		go vu.uploadWorker(in, returnChannel, client, ctx)
 	}
 
-	go func() {
-		for x := 0; x < len(vu.Paths); x++ {
-			in <- x
-		}
-	}()
-
-	countDoneWorker := 0
-	for r := range returnChannel {
-		countDoneWorker++
-		if r != "" {
-			doneUpload <- r
-			break
+	for range paths {
+		if r := <-returnChannel; r != "" {
+			log.Printf("Upload failed: %v", r)
 		}
-		if countDoneWorker == len(vu.Paths) {
-			close(in)
+	}
+
+	if len(vu.Errors) > 0 {
+		return fmt.Errorf("failed to upload %d of %d files: %s", len(vu.Errors), len(paths), strings.Join(vu.Errors, ", "))
+	}
+
+	return nil
+}
+
+// promote copies the staged objects to their final location, media first and
+// manifests last. If any copy fails, the objects already promoted are removed
+// so the encoded folder is never left half-written.
+func (vu *VideoUpload) promote(client *storage.Client, ctx context.Context) error {
+	if vu.StagingPrefix == "" {
+		return nil
+	}
+
+	bucket := client.Bucket(vu.OutputBucket)
+	var promoted []string
+
+	for _, path := range PublishOrder(vu.Paths) {
+		object := objectName(path)
+
+		copier := bucket.Object(object).CopierFrom(bucket.Object(vu.stagedName(object)))
+		vu.setObjectAttrs(&copier.ObjectAttrs, path)
+
+		if _, err := copier.Run(ctx); err != nil {
+			for _, name := range promoted {
+				deleteObject(bucket, name, ctx)
+			}
+			return fmt.Errorf("error promoting %v: %w", object, err)
 		}
+
+		promoted = append(promoted, object)
 	}
 
+	vu.discardStaging(client, ctx)
+	log.Printf("%d objects published to %v", len(promoted), vu.OutputBucket)
+
 	return nil
 }
 
-func (vu *VideoUpload) uploadWorker(in chan int, returnChannel chan string, client *storage.Client, ctx context.Context) {
-	for x := range in {
-		if err := vu.UploadObject(vu.Paths[x], client, ctx); err != nil {
-			vu.Errors = append(vu.Errors, vu.Paths[x])
-			log.Printf("Error during the upload of the file: %v. Error: %v", vu.Paths[x], err)
+// discardStaging removes every staged object of the video and forgets them
+// in the upload manifest.
+func (vu *VideoUpload) discardStaging(client *storage.Client, ctx context.Context) {
+	bucket := client.Bucket(vu.OutputBucket)
+
+	for _, path := range vu.Paths {
+		deleteObject(bucket, vu.stagedName(objectName(path)), ctx)
+	}
+
+	vu.Manifest.Reset()
+}
+
+func (vu *VideoUpload) setObjectAttrs(attrs *storage.ObjectAttrs, path string) {
+	attrs.ContentType = ContentTypeFor(path)
+	attrs.CacheControl = vu.CachePolicy.For(path)
+	attrs.Metadata = vu.Metadata
+}
+
+func (vu *VideoUpload) stagedName(object string) string {
+	if vu.StagingPrefix == "" {
+		return object
+	}
+
+	return fmt.Sprintf("%s/%s", vu.StagingPrefix, object)
+}
+
+func (vu *VideoUpload) uploadWorker(in chan string, returnChannel chan string, client *storage.Client, ctx context.Context) {
+	for path := range in {
+		object := objectName(path)
+
+		if err := vu.uploadWithRetry(path, client, ctx); err != nil {
+			vu.mutex.Lock()
+			vu.Errors = append(vu.Errors, path)
+			vu.mutex.Unlock()
+
+			vu.Manifest.MarkFailed(object)
+			log.Printf("Error during the upload of the file: %v. Error: %v", path, err)
 			returnChannel <- err.Error()
+			continue
 		}
+
+		vu.Manifest.MarkUploaded(object)
 		returnChannel <- ""
 	}
+}
+
+func (vu *VideoUpload) uploadWithRetry(path string, client *storage.Client, ctx context.Context) error {
+	var err error
+
+	// The first attempt is always made, even with a negative MaxRetries.
+	for attempt := 0; attempt <= max(vu.MaxRetries, 0); attempt++ {
+		if attempt > 0 {
+			backoff := vu.RetryBackoff * time.Duration(1<<(attempt-1))
+			log.Printf("Retrying upload of %v in %v (attempt %d/%d)", path, backoff, attempt, vu.MaxRetries)
+			time.Sleep(backoff)
+		}
+
+		if err = vu.UploadObject(path, client, ctx); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// PublishOrder sorts paths so media objects come first and manifests last.
+func PublishOrder(paths []string) []string {
+	media, manifests := splitManifests(paths)
+	return append(media, manifests...)
+}
+
+func splitManifests(paths []string) ([]string, []string) {
+	var media, manifests []string
+	for _, path := range paths {
+		if isManifest(path) {
+			manifests = append(manifests, path)
+		} else {
+			media = append(media, path)
+		}
+	}
+	sort.Strings(media)
+	sort.Strings(manifests)
+
+	return media, manifests
+}
+
+func isManifest(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mpd", ".m3u8":
+		return true
+	}
+
+	return false
+}
+
+func deleteObject(bucket *storage.BucketHandle, name string, ctx context.Context) {
+	err := bucket.Object(name).Delete(ctx)
+	if err != nil && err != storage.ErrObjectNotExist {
+		log.Printf("Error deleting object %v: %v", name, err)
+	}
+}
 
-	returnChannel <- "Upload completed"
+// objectName maps a local file path to its object name in the output bucket,
+// which is the path relative to LOCAL_STORAGE_PATH.
+func objectName(path string) string {
+	paths := strings.Split(path, fmt.Sprintf("%s/", os.Getenv("LOCAL_STORAGE_PATH")))
+	return paths[len(paths)-1]
 }
 
 func getClientUpload() (*storage.Client, context.Context, error) {
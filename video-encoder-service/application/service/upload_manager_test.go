@@ -44,3 +44,19 @@ func TestUploadServiceUpload(t *testing.T) {
 	result := <-doneUpload
 	assert.Equal(t, result, "Upload completed")
 }
+
+func TestPublishOrder_ManifestsLast(t *testing.T) {
+	paths := []string{
+		"/tmp/encoder/video/stream.mpd",
+		"/tmp/encoder/video/video/avc1/seg-2.m4s",
+		"/tmp/encoder/video/master.m3u8",
+		"/tmp/encoder/video/video/avc1/seg-1.m4s",
+	}
+
+	assert.Equal(t, []string{
+		"/tmp/encoder/video/video/avc1/seg-1.m4s",
+		"/tmp/encoder/video/video/avc1/seg-2.m4s",
+		"/tmp/encoder/video/master.m3u8",
+		"/tmp/encoder/video/stream.mpd",
+	}, service.PublishOrder(paths))
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+)
+
+// UploadManifest keeps track of which objects already landed in the output
+// bucket, so a failed upload can be resumed without re-encoding the video.
+type UploadManifest struct {
+	Uploaded []string `json:"uploaded"`
+	Failed   []string `json:"failed"`
+
+	mutex    sync.Mutex
+	uploaded map[string]bool
+	failed   map[string]bool
+}
+
+func NewUploadManifest() *UploadManifest {
+	return &UploadManifest{
+		uploaded: map[string]bool{},
+		failed:   map[string]bool{},
+	}
+}
+
+func LoadUploadManifest(path string) (*UploadManifest, error) {
+	manifest := NewUploadManifest()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+
+	for _, object := range manifest.Uploaded {
+		manifest.uploaded[object] = true
+	}
+	for _, object := range manifest.Failed {
+		manifest.failed[object] = true
+	}
+
+	return manifest, nil
+}
+
+func (m *UploadManifest) IsUploaded(object string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.uploaded[object]
+}
+
+func (m *UploadManifest) MarkUploaded(object string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.uploaded[object] = true
+	delete(m.failed, object)
+}
+
+func (m *UploadManifest) MarkFailed(object string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.uploaded[object] {
+		m.failed[object] = true
+	}
+}
+
+func (m *UploadManifest) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.uploaded = map[string]bool{}
+	m.failed = map[string]bool{}
+}
+
+func (m *UploadManifest) Save(path string) error {
+	m.mutex.Lock()
+	m.Uploaded = sortedKeys(m.uploaded)
+	m.Failed = sortedKeys(m.failed)
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
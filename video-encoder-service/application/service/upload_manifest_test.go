@@ -0,0 +1,50 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadManifest_LoadMissingFile(t *testing.T) {
+	manifest, err := service.LoadUploadManifest(filepath.Join(t.TempDir(), "missing.json"))
+
+	require.Nil(t, err)
+	assert.False(t, manifest.IsUploaded("video/stream.mpd"))
+}
+
+func TestUploadManifest_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	manifest := service.NewUploadManifest()
+	manifest.MarkUploaded("video/stream.mpd")
+	manifest.MarkFailed("video/video/avc1/seg-1.m4s")
+	require.Nil(t, manifest.Save(path))
+
+	loaded, err := service.LoadUploadManifest(path)
+
+	require.Nil(t, err)
+	assert.True(t, loaded.IsUploaded("video/stream.mpd"))
+	assert.False(t, loaded.IsUploaded("video/video/avc1/seg-1.m4s"))
+	assert.Equal(t, []string{"video/video/avc1/seg-1.m4s"}, loaded.Failed)
+}
+
+func TestVideoUpload_PendingPathsSkipsUploadedObjects(t *testing.T) {
+	t.Setenv("LOCAL_STORAGE_PATH", "/tmp/encoder")
+
+	videoUpload := service.NewVideoUpload()
+	videoUpload.Paths = []string{
+		"/tmp/encoder/video/stream.mpd",
+		"/tmp/encoder/video/video/avc1/seg-1.m4s",
+	}
+	videoUpload.Manifest.MarkUploaded("video/video/avc1/seg-1.m4s")
+
+	assert.Equal(t, []string{"/tmp/encoder/video/stream.mpd"}, videoUpload.PendingPaths())
+}
+
+func TestNewVideoUpload_KeepsStagingOnFailure(t *testing.T) {
+	assert.True(t, service.NewVideoUpload().KeepStagingOnFailure)
+}
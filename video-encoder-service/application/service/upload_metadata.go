@@ -0,0 +1,102 @@
+package service
+
+import (
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var contentTypes = map[string]string{
+	".mpd":  "application/dash+xml",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".m4s":  "video/iso.segment",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".m4a":  "audio/mp4",
+	".ts":   "video/mp2t",
+	".vtt":  "text/vtt",
+	".srt":  "application/x-subrip",
+	".ttml": "application/ttml+xml",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".json": "application/json",
+}
+
+// ContentTypeFor infers the Content-Type of an output file from its extension.
+func ContentTypeFor(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+// CachePolicy holds the Cache-Control header applied to each kind of output
+// file. Overrides are keyed by extension (".vtt") and take precedence.
+type CachePolicy struct {
+	Manifest  string
+	Segment   string
+	Default   string
+	Overrides map[string]string
+}
+
+func NewCachePolicy() CachePolicy {
+	return CachePolicy{
+		Manifest:  "public, max-age=10",
+		Segment:   "public, max-age=31536000, immutable",
+		Default:   "public, max-age=3600",
+		Overrides: map[string]string{},
+	}
+}
+
+// LoadCachePolicy reads the cache policy from the environment. Overrides are
+// given as ".ext=<cache-control>" pairs separated by semicolons.
+func LoadCachePolicy() CachePolicy {
+	policy := NewCachePolicy()
+
+	if value := os.Getenv("CACHE_CONTROL_MANIFEST"); value != "" {
+		policy.Manifest = value
+	}
+	if value := os.Getenv("CACHE_CONTROL_SEGMENT"); value != "" {
+		policy.Segment = value
+	}
+	if value := os.Getenv("CACHE_CONTROL_DEFAULT"); value != "" {
+		policy.Default = value
+	}
+
+	for _, override := range strings.Split(os.Getenv("CACHE_CONTROL_OVERRIDES"), ";") {
+		ext, value, found := strings.Cut(override, "=")
+		if !found {
+			continue
+		}
+		policy.Overrides[strings.ToLower(strings.TrimSpace(ext))] = strings.TrimSpace(value)
+	}
+
+	return policy
+}
+
+func (p CachePolicy) For(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if value, ok := p.Overrides[ext]; ok {
+		return value
+	}
+
+	switch ext {
+	case ".mpd", ".m3u8":
+		return p.Manifest
+	case ".m4s", ".mp4", ".m4v", ".m4a", ".ts":
+		return p.Segment
+	}
+
+	return p.Default
+}
@@ -0,0 +1,33 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentTypeFor(t *testing.T) {
+	assert.Equal(t, "application/dash+xml", service.ContentTypeFor("video/stream.mpd"))
+	assert.Equal(t, "application/vnd.apple.mpegurl", service.ContentTypeFor("video/master.m3u8"))
+	assert.Equal(t, "video/iso.segment", service.ContentTypeFor("video/video/avc1/seg-1.m4s"))
+	assert.Equal(t, "video/mp4", service.ContentTypeFor("video/video/avc1/init.mp4"))
+	assert.Equal(t, "text/vtt", service.ContentTypeFor("video/subtitles/en.vtt"))
+	assert.Equal(t, "image/jpeg", service.ContentTypeFor("video/thumbnails/poster.JPG"))
+	assert.Equal(t, "application/octet-stream", service.ContentTypeFor("video/unknown.xyz123"))
+}
+
+func TestCachePolicy_LoadFromEnv(t *testing.T) {
+	t.Setenv("CACHE_CONTROL_MANIFEST", "no-cache")
+	t.Setenv("CACHE_CONTROL_SEGMENT", "")
+	t.Setenv("CACHE_CONTROL_DEFAULT", "")
+	t.Setenv("CACHE_CONTROL_OVERRIDES", ".vtt=public, max-age=60; .jpg = public, max-age=86400")
+
+	policy := service.LoadCachePolicy()
+
+	assert.Equal(t, "no-cache", policy.For("video/stream.mpd"))
+	assert.Equal(t, "public, max-age=31536000, immutable", policy.For("video/video/avc1/seg-1.m4s"))
+	assert.Equal(t, "public, max-age=60", policy.For("video/subtitles/en.vtt"))
+	assert.Equal(t, "public, max-age=86400", policy.For("video/thumbnails/poster.jpg"))
+	assert.Equal(t, "public, max-age=3600", policy.For("video/thumbnails/poster.png"))
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoder/application/repository"
 	"encoder/domain"
+	"encoder/framework/drm"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"cloud.google.com/go/storage"
 )
@@ -16,61 +19,71 @@ import (
 type VideoService struct {
 	Video           *domain.Video
 	VideoRepository repository.VideoRepository
+	Options         domain.JobOptions
+	KeyProvider     drm.KeyProvider
+	Ladder          []domain.LadderRung
+	contentKey      *drm.ContentKey
 }
 
 func NewVideoService() VideoService {
 	return VideoService{}
 }
 
-func (v *VideoService) Download(bucketName string) error {
-	ctx := context.Background()
+// reset clears the state a job leaves on the service, as workers reuse the
+// same service for every job.
+func (v *VideoService) reset() {
+	v.Ladder = nil
+	v.contentKey = nil
+}
 
-	client, err := storage.NewClient(ctx)
-	if err != nil {
+func (v *VideoService) Download(bucketName string) error {
+	if err := downloadObject(bucketName, v.Video.FilePath, v.sourcePath()); err != nil {
 		return err
 	}
 
-	bkt := client.Bucket(bucketName)
-	obj := bkt.Object(v.Video.FilePath)
+	log.Printf("video %v has been downloaded", v.Video.ID)
 
-	r, err := obj.NewReader(ctx)
+	return nil
+}
+
+func downloadObject(bucketName string, objectPath string, target string) error {
+	ctx := context.Background()
+
+	client, err := storage.NewClient(ctx)
 	if err != nil {
 		return err
 	}
-	defer r.Close()
+	defer client.Close()
 
-	body, err := io.ReadAll(r)
+	r, err := client.Bucket(bucketName).Object(objectPath).NewReader(ctx)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	f, err := os.Create(fmt.Sprintf("%s/%s.mp4", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID))
+	f, err := os.Create(target)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	// print path of the file
-	log.Printf("video %v has been downloaded", f.Name())
-
-	_, err = f.Write(body)
-	if err != nil {
+	if _, err = io.Copy(f, r); err != nil {
 		return err
 	}
 
-	log.Printf("video %v has been downloaded", v.Video.ID)
+	log.Printf("object %v has been downloaded to %v", objectPath, f.Name())
 
 	return nil
 }
 
 func (v *VideoService) Fragment() error {
-	err := os.Mkdir(fmt.Sprintf("%s/%s", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID), os.ModePerm)
+	err := os.MkdirAll(v.outputPath(), os.ModePerm)
 	if err != nil {
 		return err
 	}
 
-	source := fmt.Sprintf("%s/%s.mp4", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
-	target := fmt.Sprintf("%s/%s.frag", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+	source := v.mp4Path()
+	target := v.fragPath()
 
 	cmd := exec.Command("mp4fragment", source, target)
 	output, err := cmd.CombinedOutput()
@@ -80,21 +93,20 @@ func (v *VideoService) Fragment() error {
 
 	printOutput(output)
 
+	for _, rung := range v.Ladder {
+		cmd := exec.Command("mp4fragment", v.renditionPath(rung), v.renditionFragPath(rung))
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			printOutput(output)
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (v *VideoService) Encode() error {
-	localStoragePath := os.Getenv("LOCAL_STORAGE_PATH")
-
-	cmdArgs := []string{
-		fmt.Sprintf("%s/%s.frag", localStoragePath, v.Video.ID),
-		"--use-segment-timeline",
-		"-o",
-		fmt.Sprintf("%s/%s", localStoragePath, v.Video.ID),
-		"-f",
-	}
-
-	cmd := exec.Command("mp4dash", cmdArgs...)
+	cmd := exec.Command("mp4dash", v.EncodeArgs()...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -107,32 +119,204 @@ func (v *VideoService) Encode() error {
 	return nil
 }
 
+// EncodeArgs builds the mp4dash command line: the fragmented source, or the
+// ladder renditions with the source audio, plus any extra audio and text
+// tracks requested for the job.
+func (v *VideoService) EncodeArgs() []string {
+	source := v.fragPath()
+
+	var cmdArgs []string
+	switch {
+	case len(v.Ladder) > 0:
+		for _, rung := range v.Ladder {
+			cmdArgs = append(cmdArgs, v.renditionFragPath(rung))
+		}
+		cmdArgs = append(cmdArgs, v.sourceAudioInputs(source)...)
+	case len(v.Options.AudioLanguages) > 0:
+		cmdArgs = append(cmdArgs, fmt.Sprintf("[type=video]%s", source))
+		cmdArgs = append(cmdArgs, v.sourceAudioInputs(source)...)
+	default:
+		cmdArgs = append(cmdArgs, source)
+	}
+
+	cmdArgs = append(cmdArgs,
+		"--use-segment-timeline",
+		"-o",
+		v.outputPath(),
+		"-f",
+	)
+
+	if os.Getenv("OUTPUT_HLS") == "true" {
+		cmdArgs = append(cmdArgs, "--hls")
+	}
+
+	for i, audio := range v.Options.AudioTracks {
+		cmdArgs = append(cmdArgs, AudioInput(audio, v.audioPath(i, audio)))
+	}
+
+	for i, subtitle := range v.Options.Subtitles {
+		cmdArgs = append(cmdArgs, SubtitleInput(subtitle, v.subtitlePath(i, subtitle)))
+	}
+
+	if v.Options.Encryption != nil && v.contentKey != nil {
+		cmdArgs = append(cmdArgs, EncryptionArgs(*v.Options.Encryption, *v.contentKey, v.Video.ResourceId)...)
+	}
+
+	return cmdArgs
+}
+
+func (v *VideoService) sourceAudioInputs(source string) []string {
+	var inputs []string
+
+	if len(v.Options.AudioLanguages) > 0 {
+		for _, language := range v.Options.AudioLanguages {
+			inputs = append(inputs, fmt.Sprintf("[type=audio,language=%s]%s", language, source))
+		}
+	} else if v.Video.MediaInfo.AudioTracks > 0 {
+		inputs = append(inputs, fmt.Sprintf("[type=audio]%s", source))
+	}
+
+	return inputs
+}
+
 func (v *VideoService) CleanUp() error {
 	localStoragePath := os.Getenv("LOCAL_STORAGE_PATH")
 
-	err := os.Remove(fmt.Sprintf("%s/%s.mp4", localStoragePath, v.Video.ID))
+	err := os.Remove(v.mp4Path())
 	if err != nil {
 		log.Fatalf("error removing mp4 %v", err)
 		return err
 	}
 
-	err = os.Remove(fmt.Sprintf("%s/%s.frag", localStoragePath, v.Video.ID))
+	if v.sourcePath() != v.mp4Path() {
+		err = os.Remove(v.sourcePath())
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("error removing source %v", err)
+			return err
+		}
+	}
+
+	err = os.Remove(v.fragPath())
 	if err != nil {
 		log.Fatalf("error removing frag %v", err)
 		return err
 	}
 
-	err = os.RemoveAll(fmt.Sprintf("%s/%s", localStoragePath, v.Video.ID))
+	err = os.RemoveAll(v.outputPath())
 	if err != nil {
 		log.Fatalf("error removing folder %v", err)
 		return err
 	}
 
+	if v.Options.Watermark != nil {
+		err = os.Remove(v.watermarkPath())
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("error removing watermark %v", err)
+			return err
+		}
+	}
+
+	err = os.RemoveAll(v.chunksPath())
+	if err != nil {
+		log.Printf("error removing chunks %v", err)
+		return err
+	}
+
+	err = os.RemoveAll(v.renditionsPath())
+	if err != nil {
+		log.Printf("error removing renditions %v", err)
+		return err
+	}
+
+	err = os.RemoveAll(v.audioTracksPath())
+	if err != nil {
+		log.Printf("error removing audio tracks %v", err)
+		return err
+	}
+
+	err = os.RemoveAll(v.subtitlesPath())
+	if err != nil {
+		log.Printf("error removing subtitles %v", err)
+		return err
+	}
+
+	err = os.Remove(fmt.Sprintf("%s/%s.upload.json", localStoragePath, v.Video.ID))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("error removing upload manifest %v", err)
+		return err
+	}
+
 	fmt.Printf("video %v has been removed", v.Video.ID)
 
 	return nil
 }
 
+// RemoveWorkingFiles removes whatever is left of the video in the local
+// storage, unlike CleanUp it does not expect every stage to have run.
+func (v *VideoService) RemoveWorkingFiles() error {
+	if v.Video.ID == "" {
+		return fmt.Errorf("video has no id")
+	}
+
+	paths, err := filepath.Glob(fmt.Sprintf("%s/%s*", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sourcePath is where the downloaded source is stored. The original extension
+// is kept so the container can be told apart before normalization.
+func (v *VideoService) sourcePath() string {
+	return fmt.Sprintf("%s/%s%s", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID, sourceExt(v.Video.FilePath))
+}
+
+// mp4Path is the packager-compatible MP4 that the fragmenting stage reads.
+func (v *VideoService) mp4Path() string {
+	return fmt.Sprintf("%s/%s.mp4", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+}
+
+func (v *VideoService) fragPath() string {
+	return fmt.Sprintf("%s/%s.frag", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+}
+
+// outputPath is the folder holding everything that is uploaded for the video.
+func (v *VideoService) outputPath() string {
+	return fmt.Sprintf("%s/%s", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID)
+}
+
+func sourceExt(filePath string) string {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext == "" {
+		return ".mp4"
+	}
+
+	return ext
+}
+
+func runFFmpeg(args ...string) error {
+	_, err := ffmpegOutput(args...)
+	return err
+}
+
+func ffmpegOutput(args ...string) ([]byte, error) {
+	cmd := exec.Command("ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		printOutput(output)
+		return nil, err
+	}
+
+	return output, nil
+}
+
 func printOutput(out []byte) {
 	if len(out) > 0 {
 		fmt.Println("==== OUTPUT ====")
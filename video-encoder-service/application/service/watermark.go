@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoder/domain"
+	"encoder/framework/utils"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+var watermarkPositions = map[string]string{
+	"top-left":     "%[1]d:%[1]d",
+	"top-right":    "W-w-%[1]d:%[1]d",
+	"bottom-left":  "%[1]d:H-h-%[1]d",
+	"bottom-right": "W-w-%[1]d:H-h-%[1]d",
+	"center":       "(W-w)/2:(H-h)/2",
+}
+
+// ApplyWatermark downloads the watermark image and burns it into the MP4
+// every rendition is produced from. Missing values of the watermark options
+// get their defaults.
+func (v *VideoService) ApplyWatermark(bucketName string) error {
+	watermark := v.Options.Watermark.WithDefaults()
+
+	if err := ValidateWatermark(watermark); err != nil {
+		return err
+	}
+
+	if err := downloadObject(bucketName, watermark.FilePath, v.watermarkPath()); err != nil {
+		return fmt.Errorf("error downloading watermark %v: %w", watermark.FilePath, err)
+	}
+
+	watermarked := fmt.Sprintf("%s.watermark.mp4", v.mp4Path())
+	err := runFFmpeg(
+		"-y", "-i", v.mp4Path(), "-i", v.watermarkPath(),
+		"-filter_complex", WatermarkFilter(watermark),
+		"-map", "[out]", "-map", "0:a?",
+		"-c:v", "libx264", "-preset", "medium", "-crf", "20", "-pix_fmt", "yuv420p",
+		"-c:a", "copy",
+		"-movflags", "+faststart",
+		watermarked,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(watermarked, v.mp4Path()); err != nil {
+		return err
+	}
+
+	log.Printf("watermark %v applied to video %v at %v", watermark.FilePath, v.Video.ID, watermark.Position)
+
+	return nil
+}
+
+// ValidateWatermark checks a watermark with its defaults applied. Opacity and
+// scale must be above 0, a watermark that can't be seen is an invalid
+// request, while the margin may be 0.
+func ValidateWatermark(watermark domain.Watermark) error {
+	switch {
+	case watermark.FilePath == "":
+		return NewJobError(ErrCodeInvalidRequest, errors.New("watermark file_path can't be empty"))
+	case watermarkPositions[watermark.Position] == "":
+		return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("invalid watermark position %q", watermark.Position))
+	case watermark.Opacity == nil || watermark.Scale == nil || watermark.Margin == nil:
+		return NewJobError(ErrCodeInvalidRequest, errors.New("watermark opacity, scale and margin are required"))
+	case *watermark.Opacity <= 0 || *watermark.Opacity > 1:
+		return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("watermark opacity must be between 0 and 1"))
+	case *watermark.Scale <= 0 || *watermark.Scale > 1:
+		return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("watermark scale must be between 0 and 1"))
+	case *watermark.Margin < 0:
+		return NewJobError(ErrCodeInvalidRequest, fmt.Errorf("watermark margin can't be negative"))
+	}
+
+	return nil
+}
+
+// WatermarkFilter scales the watermark relative to the video width, applies
+// its opacity and overlays it at the requested position. The watermark must
+// have its defaults applied.
+func WatermarkFilter(watermark domain.Watermark) string {
+	position := watermarkPositions[watermark.Position]
+	if watermark.Position != "center" {
+		position = fmt.Sprintf(position, *watermark.Margin)
+	}
+
+	return fmt.Sprintf(
+		"[1:v]format=rgba,colorchannelmixer=aa=%s[wm];[wm][0:v]scale2ref=w=main_w*%s:h=ow/a[wm][base];[base][wm]overlay=%s[out]",
+		utils.FormatFloat(*watermark.Opacity), utils.FormatFloat(*watermark.Scale), position,
+	)
+}
+
+func (v *VideoService) watermarkPath() string {
+	return fmt.Sprintf("%s/%s.watermark%s", os.Getenv("LOCAL_STORAGE_PATH"), v.Video.ID, sourceExt(v.Options.Watermark.FilePath))
+}
@@ -0,0 +1,55 @@
+package service_test
+
+import (
+	"encoder/application/service"
+	"encoder/domain"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatermarkFilter(t *testing.T) {
+	opacity := 0.5
+	watermark := domain.Watermark{FilePath: "logo.png", Opacity: &opacity}.WithDefaults()
+
+	assert.Equal(
+		t,
+		"[1:v]format=rgba,colorchannelmixer=aa=0.5[wm];[wm][0:v]scale2ref=w=main_w*0.1:h=ow/a[wm][base];[base][wm]overlay=W-w-20:H-h-20[out]",
+		service.WatermarkFilter(watermark),
+	)
+
+	watermark.Position = "center"
+	assert.Contains(t, service.WatermarkFilter(watermark), "overlay=(W-w)/2:(H-h)/2[out]")
+}
+
+func TestValidateWatermark(t *testing.T) {
+	assert.Nil(t, service.ValidateWatermark(domain.Watermark{FilePath: "logo.png"}.WithDefaults()))
+
+	invalidPosition := domain.Watermark{FilePath: "logo.png", Position: "middle"}.WithDefaults()
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateWatermark(invalidPosition)))
+
+	opacity := 2.0
+	invalidOpacity := domain.Watermark{FilePath: "logo.png", Opacity: &opacity}.WithDefaults()
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateWatermark(invalidOpacity)))
+
+	opacity = 0
+	zeroOpacity := domain.Watermark{FilePath: "logo.png", Opacity: &opacity}.WithDefaults()
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateWatermark(zeroOpacity)))
+
+	scale := 0.0
+	zeroScale := domain.Watermark{FilePath: "logo.png", Scale: &scale}.WithDefaults()
+	assert.Equal(t, service.ErrCodeInvalidRequest, service.ErrorCode(service.ValidateWatermark(zeroScale)))
+}
+
+func TestWatermark_ExplicitZeroMargin(t *testing.T) {
+	var watermark domain.Watermark
+	require.Nil(t, json.Unmarshal([]byte(`{"file_path": "logo.png", "margin": 0}`), &watermark))
+
+	watermark = watermark.WithDefaults()
+
+	assert.Nil(t, service.ValidateWatermark(watermark))
+	assert.Equal(t, 0, *watermark.Margin)
+	assert.Contains(t, service.WatermarkFilter(watermark), "overlay=W-w-0:H-h-0[out]")
+}
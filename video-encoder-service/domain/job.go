@@ -5,6 +5,7 @@ import (
 
 	"github.com/asaskevich/govalidator"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -12,20 +13,31 @@ func init() {
 }
 
 type Job struct {
-	ID               string    `json:"job_id" valid:"uuid" gorm:"type:uuid;primary_key"`
-	OutputBucketPath string    `json:"output_bucket_path" valid:"notnull"`
-	Status           string    `json:"status" valid:"notnull"`
-	Video            *Video    `json:"video" valid:"-"`
-	VideoId          string    `json:"-" valid:"-" gorm:"column:video_id;type:uuid;notnull"`
-	Error            string    `json:"-" valid:"-"`
-	CreatedAt        time.Time `json:"created_at" valid:"-"`
-	UpdateAt         time.Time `json:"updated_at" valid:"-"`
+	ID               string          `json:"job_id" valid:"uuid" gorm:"type:uuid;primary_key"`
+	OutputBucketPath string          `json:"output_bucket_path" valid:"notnull"`
+	Status           string          `json:"status" valid:"notnull"`
+	Video            *Video          `json:"video" valid:"-"`
+	VideoId          string          `json:"-" valid:"-" gorm:"column:video_id;type:uuid;notnull"`
+	Error            string          `json:"-" valid:"-"`
+	Options          JobOptions      `json:"options" valid:"-" gorm:"serializer:json"`
+	Ladder           *EncodingLadder `json:"ladder,omitempty" valid:"-" gorm:"serializer:json"`
+	ParentJobId      *string         `json:"parent_job_id,omitempty" valid:"-" gorm:"column:parent_job_id;type:uuid;index"`
+	ChunkIndex       int             `json:"chunk_index,omitempty" valid:"-"`
+	Version          int             `json:"version" valid:"-" gorm:"notnull"`
+	Priority         int             `json:"priority" valid:"-" gorm:"notnull"`
+	Tenant           string          `json:"tenant,omitempty" valid:"-"`
+	WorkerID         string          `json:"-" valid:"-"`
+	HeartbeatAt      *time.Time      `json:"-" valid:"-"`
+	CreatedAt        time.Time       `json:"created_at" valid:"-"`
+	UpdatedAt        time.Time       `json:"updated_at" valid:"-"`
+	DeletedAt        gorm.DeletedAt  `json:"-" valid:"-" gorm:"index"`
 }
 
 func (job *Job) prepare() {
 	job.ID = uuid.New().String()
 	job.CreatedAt = time.Now()
-	job.UpdateAt = time.Now()
+	job.UpdatedAt = time.Now()
+	job.Version = 1
 }
 
 func NewJob(outputBucketPath string, status string, video *Video) (*Job, error) {
@@ -51,3 +63,17 @@ func (job *Job) Validate() error {
 	}
 	return nil
 }
+
+// NewChunkJob creates the child job that tracks the encoding of one chunk of
+// the parent job's video.
+func NewChunkJob(parent *Job, chunkIndex int) (*Job, error) {
+	job, err := NewJob(parent.OutputBucketPath, "PENDING", parent.Video)
+	if err != nil {
+		return nil, err
+	}
+
+	job.ParentJobId = &parent.ID
+	job.ChunkIndex = chunkIndex
+
+	return job, nil
+}
@@ -0,0 +1,158 @@
+package domain
+
+import "fmt"
+
+// JobOptions holds the optional processing steps and outputs requested in the
+// encode message, next to resource_id and file_path. Ladder is "default" or
+// "per-title" to encode a bitrate ladder instead of packaging the source as a
+// single rendition. Jobs with a higher Priority run first, and Tenant is the
+// owner the fair-share scheduler caps concurrent jobs for. Attempt is not read
+// from the message: it counts how many times the job was re-enqueued after
+// its worker died.
+type JobOptions struct {
+	Sprites        *SpriteOptions  `json:"sprites,omitempty" valid:"-"`
+	Subtitles      []SubtitleTrack `json:"subtitles,omitempty" valid:"-"`
+	AudioTracks    []AudioTrack    `json:"audio_tracks,omitempty" valid:"-"`
+	AudioLanguages []string        `json:"audio_languages,omitempty" valid:"-"`
+	Encryption     *Encryption     `json:"encryption,omitempty" valid:"-"`
+	Loudness       *Loudness       `json:"loudness,omitempty" valid:"-"`
+	Watermark      *Watermark      `json:"watermark,omitempty" valid:"-"`
+	Trim           []ClipRange     `json:"trim,omitempty" valid:"-"`
+	Clips          []ClipRange     `json:"clips,omitempty" valid:"-"`
+	Ladder         string          `json:"ladder,omitempty" valid:"-"`
+	Chunked        *ChunkOptions   `json:"chunked,omitempty" valid:"-"`
+	Priority       int             `json:"priority,omitempty" valid:"-"`
+	Tenant         string          `json:"tenant,omitempty" valid:"-"`
+	Attempt        int             `json:"attempt,omitempty" valid:"-"`
+}
+
+// SpriteOptions configures the seek bar preview: a frame is sampled every
+// Interval seconds and tiled Columns x Rows per sprite image.
+type SpriteOptions struct {
+	Interval int `json:"interval" valid:"-"`
+	Width    int `json:"width" valid:"-"`
+	Height   int `json:"height" valid:"-"`
+	Columns  int `json:"columns" valid:"-"`
+	Rows     int `json:"rows" valid:"-"`
+}
+
+func (o SpriteOptions) WithDefaults() SpriteOptions {
+	if o.Interval <= 0 {
+		o.Interval = 10
+	}
+	if o.Width <= 0 {
+		o.Width = 160
+	}
+	if o.Height <= 0 {
+		o.Height = 90
+	}
+	if o.Columns <= 0 {
+		o.Columns = 10
+	}
+	if o.Rows <= 0 {
+		o.Rows = 10
+	}
+
+	return o
+}
+
+// SubtitleTrack is an SRT or WebVTT file in the input bucket packaged as a
+// text track. Language is an ISO 639 code such as "en" or "pt-BR".
+type SubtitleTrack struct {
+	FilePath string `json:"file_path" valid:"-"`
+	Language string `json:"language" valid:"-"`
+	Label    string `json:"label,omitempty" valid:"-"`
+}
+
+// AudioTrack is an extra audio file in the input bucket, such as a dubbing,
+// packaged as its own adaptation set. Role follows the DASH role scheme
+// ("main", "dub", "alternate", "commentary", "description").
+type AudioTrack struct {
+	FilePath string `json:"file_path" valid:"-"`
+	Language string `json:"language" valid:"-"`
+	Label    string `json:"label,omitempty" valid:"-"`
+	Role     string `json:"role,omitempty" valid:"-"`
+}
+
+// Encryption requests Common Encryption of the packaged output. Scheme is
+// "cenc" (default) or "cbcs"; the DRM systems select which PSSH boxes and
+// ContentProtection elements are written to the manifest.
+type Encryption struct {
+	Scheme    string `json:"scheme,omitempty" valid:"-"`
+	Widevine  bool   `json:"widevine" valid:"-"`
+	PlayReady bool   `json:"playready" valid:"-"`
+}
+
+// Loudness requests EBU R128 loudness normalization. Zero values fall back
+// to the service defaults.
+type Loudness struct {
+	TargetLUFS float64 `json:"target_lufs,omitempty" valid:"-"`
+	TruePeak   float64 `json:"true_peak,omitempty" valid:"-"`
+	Range      float64 `json:"range,omitempty" valid:"-"`
+}
+
+// Watermark is an image in the input bucket burned into every rendition.
+// Position is one of "top-left", "top-right", "bottom-left", "bottom-right"
+// or "center"; Scale is the watermark width as a fraction of the video width.
+// Opacity and Scale must be above 0 and at most 1, only Margin may be zero.
+// They are pointers so an explicit zero is told apart from a missing value:
+// a zero margin is kept and a zero opacity or scale is rejected instead of
+// replaced by the default.
+type Watermark struct {
+	FilePath string   `json:"file_path" valid:"-"`
+	Position string   `json:"position,omitempty" valid:"-"`
+	Opacity  *float64 `json:"opacity,omitempty" valid:"-"`
+	Scale    *float64 `json:"scale,omitempty" valid:"-"`
+	Margin   *int     `json:"margin,omitempty" valid:"-"`
+}
+
+// WithDefaults fills the missing values: bottom-right, fully opaque, a tenth
+// of the video width and 20 pixels from the edges.
+func (w Watermark) WithDefaults() Watermark {
+	if w.Position == "" {
+		w.Position = "bottom-right"
+	}
+	if w.Opacity == nil {
+		opacity := 1.0
+		w.Opacity = &opacity
+	}
+	if w.Scale == nil {
+		scale := 0.1
+		w.Scale = &scale
+	}
+	if w.Margin == nil {
+		margin := 20
+		w.Margin = &margin
+	}
+
+	return w
+}
+
+// ClipRange is a segment of the source between Start and End, in seconds.
+// Trim ranges are joined in order to build the packaged output; clips are
+// exported as standalone MP4 previews.
+type ClipRange struct {
+	Start float64 `json:"start" valid:"-"`
+	End   float64 `json:"end" valid:"-"`
+	Name  string  `json:"name,omitempty" valid:"-"`
+}
+
+func (c ClipRange) Duration() float64 {
+	return c.End - c.Start
+}
+
+// FileName is the name the clip is exported under, "clip_<n>" when it has
+// none, n counting from 1.
+func (c ClipRange) FileName(index int) string {
+	if c.Name != "" {
+		return c.Name
+	}
+
+	return fmt.Sprintf("clip_%d", index+1)
+}
+
+// ChunkOptions requests split-encode-stitch: the source is split at key
+// frames into chunks of about Seconds, which are encoded in parallel.
+type ChunkOptions struct {
+	Seconds int `json:"seconds,omitempty" valid:"-"`
+}
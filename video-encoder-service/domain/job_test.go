@@ -18,3 +18,20 @@ func TestNewJob(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, job.ID)
 }
+
+func TestNewChunkJob(t *testing.T) {
+	video := domain.NewVideo()
+	video.ID = uuid.New().String()
+	video.FilePath = "path"
+
+	parent, err := domain.NewJob("path", "TRANSCODING", video)
+	assert.Nil(t, err)
+
+	chunk, err := domain.NewChunkJob(parent, 3)
+
+	assert.Nil(t, err)
+	assert.NotEqual(t, parent.ID, chunk.ID)
+	assert.Equal(t, parent.ID, *chunk.ParentJobId)
+	assert.Equal(t, 3, chunk.ChunkIndex)
+	assert.Equal(t, "PENDING", chunk.Status)
+}
@@ -0,0 +1,25 @@
+package domain
+
+// LadderRung is one video rendition of an encoding ladder. Bitrate is in
+// kbps.
+type LadderRung struct {
+	Height  int `json:"height" valid:"-"`
+	Bitrate int `json:"bitrate" valid:"-"`
+}
+
+// EncodingLadder records the ladder chosen for a job next to the default one,
+// so per-title decisions can be compared with the fixed ladder.
+type EncodingLadder struct {
+	Mode       string       `json:"mode" valid:"-"`
+	Complexity float64      `json:"complexity" valid:"-"`
+	Rungs      []LadderRung `json:"rungs" valid:"-"`
+	Default    []LadderRung `json:"default" valid:"-"`
+}
+
+var DefaultLadder = []LadderRung{
+	{Height: 1080, Bitrate: 5000},
+	{Height: 720, Bitrate: 3000},
+	{Height: 480, Bitrate: 1200},
+	{Height: 360, Bitrate: 700},
+	{Height: 240, Bitrate: 400},
+}
@@ -0,0 +1,19 @@
+package domain
+
+// MediaInfo describes the source media as reported by the probe stage.
+type MediaInfo struct {
+	Container      string   `json:"container" valid:"-"`
+	Duration       float64  `json:"duration" valid:"-"`
+	Width          int      `json:"width" valid:"-"`
+	Height         int      `json:"height" valid:"-"`
+	VideoCodec     string   `json:"video_codec" valid:"-"`
+	AudioCodec     string   `json:"audio_codec" valid:"-"`
+	FrameRate      float64  `json:"frame_rate" valid:"-"`
+	Bitrate        int64    `json:"bitrate" valid:"-"`
+	AudioTracks    int      `json:"audio_tracks" valid:"-"`
+	AudioLanguages []string `json:"audio_languages" valid:"-" gorm:"serializer:json"`
+}
+
+func (m MediaInfo) HasVideo() bool {
+	return m.VideoCodec != ""
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification is a message waiting in the outbox until it is delivered to
+// its exchange. It is used when jobs are fed from the database instead of
+// RabbitMQ.
+type Notification struct {
+	ID          string     `json:"id" valid:"uuid" gorm:"type:uuid;primary_key"`
+	Exchange    string     `json:"exchange" valid:"-"`
+	RoutingKey  string     `json:"routing_key" valid:"-"`
+	ContentType string     `json:"content_type" valid:"-"`
+	Body        string     `json:"body" valid:"notnull" gorm:"notnull"`
+	CreatedAt   time.Time  `json:"created_at" valid:"-"`
+	PublishedAt *time.Time `json:"published_at,omitempty" valid:"-"`
+}
+
+func NewNotification(body string, contentType string, exchange string, routingKey string) *Notification {
+	return &Notification{
+		ID:          uuid.New().String(),
+		Exchange:    exchange,
+		RoutingKey:  routingKey,
+		ContentType: contentType,
+		Body:        body,
+		CreatedAt:   time.Now(),
+	}
+}
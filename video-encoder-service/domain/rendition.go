@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rendition is one representation of the packaged output: a video quality,
+// an audio track or a text track. Path is the object prefix holding its
+// segments in the output bucket.
+type Rendition struct {
+	ID           string    `json:"-" valid:"uuid" gorm:"type:uuid;primary_key"`
+	VideoId      string    `json:"-" valid:"-" gorm:"column:video_id;type:uuid;notnull;index"`
+	Type         string    `json:"type" valid:"-"`
+	Codec        string    `json:"codec" valid:"-"`
+	Width        int       `json:"width,omitempty" valid:"-"`
+	Height       int       `json:"height,omitempty" valid:"-"`
+	Bitrate      int64     `json:"bitrate,omitempty" valid:"-"`
+	Language     string    `json:"language,omitempty" valid:"-"`
+	SegmentCount int       `json:"segment_count" valid:"-"`
+	TotalBytes   int64     `json:"total_bytes" valid:"-"`
+	Path         string    `json:"path" valid:"-"`
+	CreatedAt    time.Time `json:"-" valid:"-"`
+}
+
+func NewRendition(videoID string) *Rendition {
+	return &Rendition{
+		ID:        uuid.New().String(),
+		VideoId:   videoID,
+		CreatedAt: time.Now(),
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"gorm.io/gorm"
 )
 
 var (
@@ -11,11 +12,21 @@ var (
 )
 
 type Video struct {
-	ID         string    `json:"encoded_video_folder" valid:"uuid" gorm:"type:uuid;primary_key"`
-	ResourceId string    `json:"resource_id" valid:"notnull" gorm:"type:uuid;notnull"`
-	FilePath   string    `json:"file_path" valid:"notnull" gorm:"notnull"`
-	CreatedAt  time.Time `json:"-" valid:"-" gorm:"notnull"`
-	Jobs       []*Job    `json:"-" valid:"-" gorm:"ForeignKey:VideoId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ID              string         `json:"encoded_video_folder" valid:"uuid" gorm:"type:uuid;primary_key"`
+	ResourceId      string         `json:"resource_id" valid:"notnull" gorm:"type:uuid;notnull"`
+	FilePath        string         `json:"file_path" valid:"notnull" gorm:"notnull"`
+	MediaInfo       MediaInfo      `json:"media_info" valid:"-" gorm:"embedded;embeddedPrefix:source_"`
+	Thumbnails      []string       `json:"thumbnails" valid:"-" gorm:"serializer:json"`
+	SpriteTrack     string         `json:"sprite_track,omitempty" valid:"-"`
+	Clips           []string       `json:"clips,omitempty" valid:"-" gorm:"serializer:json"`
+	TrimmedDuration float64        `json:"trimmed_duration,omitempty" valid:"-"`
+	Loudness        *float64       `json:"integrated_loudness,omitempty" valid:"-" gorm:"column:integrated_loudness"`
+	DashManifest    string         `json:"dash_manifest,omitempty" valid:"-"`
+	HlsManifest     string         `json:"hls_manifest,omitempty" valid:"-"`
+	Renditions      []*Rendition   `json:"renditions,omitempty" valid:"-" gorm:"ForeignKey:VideoId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	CreatedAt       time.Time      `json:"-" valid:"-" gorm:"notnull"`
+	DeletedAt       gorm.DeletedAt `json:"-" valid:"-" gorm:"index"`
+	Jobs            []*Job         `json:"-" valid:"-" gorm:"ForeignKey:VideoId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
 func init() {
@@ -28,6 +39,16 @@ func NewVideo() *Video {
 	}
 }
 
+// PackagedDuration is the length of the encoded output: the trimmed length
+// when the job trimmed the source, the source duration otherwise.
+func (video *Video) PackagedDuration() float64 {
+	if video.TrimmedDuration > 0 {
+		return video.TrimmedDuration
+	}
+
+	return video.MediaInfo.Duration
+}
+
 func (video *Video) Validate() error {
 	_, err := govalidator.ValidateStruct(video)
 	if err != nil {
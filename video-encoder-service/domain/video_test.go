@@ -38,3 +38,13 @@ func TestValidateVideo(t *testing.T) {
 
 	require.Nil(t, err)
 }
+
+func TestVideo_PackagedDuration(t *testing.T) {
+	video := domain.NewVideo()
+	video.MediaInfo.Duration = 120
+	require.Equal(t, 120.0, video.PackagedDuration())
+
+	video.TrimmedDuration = 30
+	require.Equal(t, 30.0, video.PackagedDuration())
+	require.Equal(t, 120.0, video.MediaInfo.Duration)
+}
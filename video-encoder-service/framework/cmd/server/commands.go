@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoder/application/repository"
+	"encoder/application/service"
+	"encoder/framework/database"
+	"encoder/framework/queue"
+	"encoder/framework/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func runCommand(dbConnection *gorm.DB, args []string) {
+	switch args[0] {
+	case "migrate":
+		if err := migrate(dbConnection, args[1:]); err != nil {
+			log.Fatalf("error migrating the database: %v", err)
+		}
+	case "retry-upload":
+		requireMigratedSchema(dbConnection)
+
+		if len(args) < 2 {
+			log.Fatalf("usage: server retry-upload <job_id>")
+		}
+		if err := retryUpload(dbConnection, args[1]); err != nil {
+			log.Fatalf("error retrying upload of job %v: %v", args[1], err)
+		}
+	case "enqueue":
+		requireMigratedSchema(dbConnection)
+		if len(args) < 2 {
+			log.Fatalf("usage: server enqueue <message.json|->")
+		}
+		if err := enqueue(dbConnection, args[1]); err != nil {
+			log.Fatalf("error enqueuing %v: %v", args[1], err)
+		}
+	case "purge":
+		requireMigratedSchema(dbConnection)
+		if len(args) < 2 {
+			log.Fatalf("usage: server purge <video_id>")
+		}
+		if err := purge(dbConnection, args[1]); err != nil {
+			log.Fatalf("error purging video %v: %v", args[1], err)
+		}
+	case "retention":
+		requireMigratedSchema(dbConnection)
+		if err := applyRetention(dbConnection); err != nil {
+			log.Fatalf("error applying the retention policy: %v", err)
+		}
+	case "relay-notifications":
+		requireMigratedSchema(dbConnection)
+		if err := relayNotifications(dbConnection); err != nil {
+			log.Fatalf("error relaying notifications: %v", err)
+		}
+	default:
+		log.Fatalf("unknown command %q", args[0])
+	}
+}
+
+func requireMigratedSchema(dbConnection *gorm.DB) {
+	if err := database.CheckSchema(dbConnection); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func migrate(dbConnection *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: server migrate up|down [steps]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(dbConnection)
+		if err != nil {
+			return err
+		}
+		log.Printf("%d migrations applied", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		return database.MigrateDown(dbConnection, steps)
+	case "status":
+		pending, err := database.PendingMigrations(dbConnection)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			log.Printf("pending migration %d_%s", migration.Version, migration.Name)
+		}
+		log.Printf("%d migrations pending", len(pending))
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	return nil
+}
+
+func newRetention(dbConnection *gorm.DB) (*service.Retention, error) {
+	return service.NewRetention(
+		repository.NewJobRepository(dbConnection),
+		repository.NewVideoRepository(dbConnection),
+	)
+}
+
+// purge deletes the outputs of a video from the bucket and soft-deletes it.
+func purge(dbConnection *gorm.DB, videoID string) error {
+	retention, err := newRetention(dbConnection)
+	if err != nil {
+		return err
+	}
+
+	_, err = retention.Purge(videoID)
+	return err
+}
+
+// applyRetention runs the retention policy once, e.g. from a cron job.
+func applyRetention(dbConnection *gorm.DB) error {
+	retention, err := newRetention(dbConnection)
+	if err != nil {
+		return err
+	}
+	if retention.MaxAge <= 0 {
+		return fmt.Errorf("RETENTION_DAYS is not set")
+	}
+
+	_, err = retention.Apply()
+	return err
+}
+
+// relayNotifications delivers the notifications written to the outbox in
+// database queue mode to RabbitMQ, every OUTBOX_RELAY_INTERVAL_SECONDS.
+func relayNotifications(dbConnection *gorm.DB) error {
+	interval, err := utils.EnvInt("OUTBOX_RELAY_INTERVAL_SECONDS", 5)
+	if err != nil {
+		return err
+	}
+
+	rabbitMQ := queue.NewRabbitMQ()
+	ch := rabbitMQ.Connect()
+	defer ch.Close()
+
+	relay, err := service.NewOutboxRelay(repository.NewNotificationRepository(dbConnection), rabbitMQ)
+	if err != nil {
+		return err
+	}
+
+	relay.Run(time.Duration(interval) * time.Second)
+	return nil
+}
+
+// enqueue adds the encode message read from a file, or from stdin with "-",
+// to the queue selected by JOB_QUEUE_DRIVER.
+func enqueue(dbConnection *gorm.DB, path string) error {
+	var message []byte
+	var err error
+	if path == "-" {
+		message, err = io.ReadAll(os.Stdin)
+	} else {
+		message, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	queueDriver, err := service.QueueDriver()
+	if err != nil {
+		return err
+	}
+
+	if queueDriver == service.QueueDriverDatabase {
+		jobQueue := service.DatabaseJobQueue{
+			JobRepository:   repository.NewJobRepository(dbConnection),
+			VideoRepository: repository.NewVideoRepository(dbConnection),
+		}
+
+		job, err := jobQueue.Insert(message)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("job %v enqueued", job.ID)
+		return nil
+	}
+
+	rabbitMQ := queue.NewRabbitMQ()
+	ch := rabbitMQ.Connect()
+	defer ch.Close()
+
+	jobQueue := service.RabbitMQJobQueue{Publisher: rabbitMQ, QueueName: rabbitMQ.ConsumerQueueName}
+	if err := jobQueue.Enqueue(message); err != nil {
+		return err
+	}
+
+	log.Printf("message published to %v", rabbitMQ.ConsumerQueueName)
+	return nil
+}
+
+func retryUpload(dbConnection *gorm.DB, jobID string) error {
+	jobRepository := repository.NewJobRepository(dbConnection)
+
+	job, err := jobRepository.Find(jobID)
+	if err != nil {
+		return err
+	}
+
+	jobService := service.JobService{
+		Job:           job,
+		JobRepository: jobRepository,
+		VideoService: service.VideoService{
+			Video:           job.Video,
+			VideoRepository: repository.NewVideoRepository(dbConnection),
+		},
+	}
+
+	if err := jobService.RetryUpload(); err != nil {
+		return err
+	}
+
+	jobJson, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	publisher, closePublisher, err := notificationPublisher(dbConnection)
+	if err != nil {
+		return err
+	}
+	defer closePublisher()
+
+	return publisher.Notify(
+		string(jobJson),
+		"application/json",
+		os.Getenv("RABBITMQ_NOTIFICATION_EX"),
+		os.Getenv("RABBITMQ_NOTIFICATION_ROUTING_KEY"),
+	)
+}
+
+// notificationPublisher returns where notifications go with the configured
+// queue driver, and the function releasing it.
+func notificationPublisher(dbConnection *gorm.DB) (service.Publisher, func(), error) {
+	queueDriver, err := service.QueueDriver()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if queueDriver == service.QueueDriverDatabase {
+		publisher := service.OutboxPublisher{
+			NotificationRepository: repository.NewNotificationRepository(dbConnection),
+		}
+
+		return publisher, func() {}, nil
+	}
+
+	rabbitMQ := queue.NewRabbitMQ()
+	ch := rabbitMQ.Connect()
+
+	return rabbitMQ, func() { ch.Close() }, nil
+}
@@ -4,9 +4,11 @@ import (
 	"encoder/application/service"
 	"encoder/framework/database"
 	"encoder/framework/queue"
+	"encoder/framework/utils"
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/streadway/amqp"
@@ -35,6 +37,22 @@ func init() {
 	db.DsnTest = os.Getenv("DSN_TEST")
 	db.Dsn = os.Getenv("DSN")
 	db.Env = os.Getenv("ENV")
+
+	db.MaxOpenConns = envInt("DB_MAX_OPEN_CONNS", 0)
+	db.MaxIdleConns = envInt("DB_MAX_IDLE_CONNS", 0)
+	db.ConnMaxLifetime = time.Duration(envInt("DB_CONN_MAX_LIFETIME_SECONDS", 0)) * time.Second
+	db.ConnMaxIdleTime = time.Duration(envInt("DB_CONN_MAX_IDLE_TIME_SECONDS", 0)) * time.Second
+	db.ConnectRetries = envInt("DB_CONNECT_RETRIES", 5)
+	db.ConnectBackoff = time.Duration(envInt("DB_CONNECT_BACKOFF_SECONDS", 1)) * time.Second
+}
+
+func envInt(key string, fallback int) int {
+	value, err := utils.EnvInt(key, fallback)
+	if err != nil {
+		log.Fatalf("Error parsing the %s: %v", key, err)
+	}
+
+	return value
 }
 
 func main() {
@@ -43,10 +61,35 @@ func main() {
 
 	dbConnection, err := db.Connect()
 	if err != nil {
-		log.Fatalf("error connecting to the database")
+		log.Fatalf("error connecting to the database: %v", err)
+	}
+
+	if len(os.Args) > 1 {
+		runCommand(dbConnection, os.Args[1:])
+		return
+	}
+
+	requireMigratedSchema(dbConnection)
+
+	queueDriver, err := service.QueueDriver()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if queueDriver == service.QueueDriverDatabase {
+		jobManager := service.NewDatabaseJobManager(dbConnection, jobReturnChannel)
+		jobManager.Start(nil)
+		return
 	}
 
 	rabbitMQ := queue.NewRabbitMQ()
+	if rabbitMQ.PrefetchCount == 0 {
+		rabbitMQ.PrefetchCount, err = service.DispatchPrefetch()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
 	ch := rabbitMQ.Connect()
 	defer ch.Close()
 
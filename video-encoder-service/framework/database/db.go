@@ -1,8 +1,8 @@
 package database
 
 import (
-	"encoder/domain"
 	"log"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -16,8 +16,23 @@ type Database struct {
 	Debug       bool
 	AutoMigrate bool
 	Env         string
+
+	// Pool settings of the underlying sql.DB. Zero keeps the database/sql
+	// default.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectRetries is how many times Connect retries opening the
+	// database, waiting ConnectBackoff and then twice as long each time, up
+	// to maxConnectBackoff.
+	ConnectRetries int
+	ConnectBackoff time.Duration
 }
 
+const maxConnectBackoff = 30 * time.Second
+
 func NewDb() *Database {
 	return &Database{}
 }
@@ -39,23 +54,77 @@ func NewDbTest() *gorm.DB {
 func (db *Database) Connect() (*gorm.DB, error) {
 	var err error
 
-	if db.Env == "test" {
-		db.Db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	} else {
-		db.Db, err = gorm.Open(postgres.Open(db.Dsn), &gorm.Config{})
+	for attempt := 0; ; attempt++ {
+		db.Db, err = db.open()
+		if err == nil || attempt >= db.ConnectRetries {
+			break
+		}
+
+		wait := db.backoff(attempt)
+		log.Printf("error connecting to the database, retrying in %v (%d/%d): %v", wait, attempt+1, db.ConnectRetries, err)
+		time.Sleep(wait)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
+	if err := db.configurePool(); err != nil {
+		return nil, err
+	}
+
 	if db.Debug {
 		db.Db = db.Db.Debug()
 	}
 
 	if db.AutoMigrate {
-		db.Db.AutoMigrate(&domain.Video{}, &domain.Job{})
+		if _, err := MigrateUp(db.Db); err != nil {
+			return nil, err
+		}
 	}
 
 	return db.Db, nil
 }
+
+func (db *Database) open() (*gorm.DB, error) {
+	if db.Env == "test" {
+		return gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	}
+
+	return gorm.Open(postgres.Open(db.Dsn), &gorm.Config{})
+}
+
+func (db *Database) backoff(attempt int) time.Duration {
+	wait := db.ConnectBackoff
+	if wait <= 0 {
+		wait = time.Second
+	}
+
+	for i := 0; i < attempt && wait < maxConnectBackoff; i++ {
+		wait *= 2
+	}
+
+	return min(wait, maxConnectBackoff)
+}
+
+func (db *Database) configurePool() error {
+	sqlDB, err := db.Db.DB()
+	if err != nil {
+		return err
+	}
+
+	if db.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(db.MaxOpenConns)
+	}
+	if db.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(db.MaxIdleConns)
+	}
+	if db.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(db.ConnMaxLifetime)
+	}
+	if db.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(db.ConnMaxIdleTime)
+	}
+
+	return nil
+}
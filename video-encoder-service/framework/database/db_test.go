@@ -0,0 +1,52 @@
+package database_test
+
+import (
+	"encoder/framework/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnect_ConfiguresPool(t *testing.T) {
+	db := database.NewDb()
+	db.Env = "test"
+	db.MaxOpenConns = 1
+
+	conn, err := db.Connect()
+	require.Nil(t, err)
+
+	sqlDB, err := conn.DB()
+	require.Nil(t, err)
+
+	assert.Equal(t, 1, sqlDB.Stats().MaxOpenConnections)
+}
+
+func TestConnect_RetriesThenFails(t *testing.T) {
+	db := database.NewDb()
+	db.Dsn = "host=127.0.0.1 port=1 user=encoder dbname=encoder sslmode=disable connect_timeout=1"
+	db.ConnectRetries = 2
+	db.ConnectBackoff = 10 * time.Millisecond
+
+	start := time.Now()
+	_, err := db.Connect()
+
+	assert.NotNil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+func TestHealthChecker(t *testing.T) {
+	conn := newEmptyDb(t)
+	checker := database.NewHealthChecker(conn, time.Second)
+
+	assert.Nil(t, checker.Check())
+	assert.True(t, checker.Healthy())
+
+	sqlDB, err := conn.DB()
+	require.Nil(t, err)
+	sqlDB.Close()
+
+	assert.NotNil(t, checker.Check())
+	assert.False(t, checker.Healthy())
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HealthChecker pings the database periodically and keeps the result, so
+// workers can stop pulling new work while the database is unreachable.
+type HealthChecker struct {
+	Db      *gorm.DB
+	Timeout time.Duration
+
+	unhealthy atomic.Bool
+}
+
+func NewHealthChecker(db *gorm.DB, timeout time.Duration) *HealthChecker {
+	return &HealthChecker{Db: db, Timeout: timeout}
+}
+
+// Healthy reports the result of the last check. The database is assumed
+// healthy until a check fails.
+func (h *HealthChecker) Healthy() bool {
+	return !h.unhealthy.Load()
+}
+
+// Check pings the database and updates the health status.
+func (h *HealthChecker) Check() error {
+	err := h.ping()
+
+	wasHealthy := !h.unhealthy.Swap(err != nil)
+	if err != nil && wasHealthy {
+		log.Printf("database is unhealthy: %v", err)
+	}
+	if err == nil && !wasHealthy {
+		log.Printf("database is healthy again")
+	}
+
+	return err
+}
+
+func (h *HealthChecker) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.Check()
+	}
+}
+
+func (h *HealthChecker) ping() error {
+	sqlDB, err := h.Db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	return sqlDB.PingContext(ctx)
+}
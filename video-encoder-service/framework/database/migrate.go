@@ -0,0 +1,236 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+var ErrSchemaNotMigrated = errors.New("database schema is not up to date, run the migrate up command")
+
+// Migration is a versioned schema change, read from
+// migrations/{dialect}/{version}_{name}.{up|down}.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// LoadMigrations returns the migrations embedded for a dialect, ordered by
+// version.
+func LoadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %v: %w", dialect, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %v", entry.Name())
+		}
+
+		prefix, name, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %v", entry.Name())
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		switch direction {
+		case "up":
+			migration.Up = string(content)
+		case "down":
+			migration.Down = string(content)
+		default:
+			return nil, fmt.Errorf("invalid migration direction in %v", entry.Name())
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration, each one in its own
+// transaction, and returns how many were applied.
+func MigrateUp(conn *gorm.DB) (int, error) {
+	pending, err := PendingMigrations(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, migration := range pending {
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, migration.Up); err != nil {
+				return err
+			}
+
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("error applying migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		log.Printf("applied migration %d_%s", migration.Version, migration.Name)
+	}
+
+	return len(pending), nil
+}
+
+// MigrateDown rolls back the last applied migrations, newest first.
+func MigrateDown(conn *gorm.DB, steps int) error {
+	migrations, err := LoadMigrations(conn.Dialector.Name())
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedVersions(conn)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		migration := migrations[i]
+		if !applied[migration.Version] {
+			continue
+		}
+
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, migration.Down); err != nil {
+				return err
+			}
+
+			return tx.Delete(&schemaMigration{Version: migration.Version}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("error rolling back migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+
+		log.Printf("rolled back migration %d_%s", migration.Version, migration.Name)
+		steps--
+	}
+
+	return nil
+}
+
+// PendingMigrations returns the embedded migrations not yet recorded in
+// schema_migrations.
+func PendingMigrations(conn *gorm.DB) ([]Migration, error) {
+	migrations, err := LoadMigrations(conn.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedVersions(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// CheckSchema returns ErrSchemaNotMigrated when migrations are pending, so
+// workers never run against a schema older than the code.
+func CheckSchema(conn *gorm.DB) error {
+	pending, err := PendingMigrations(conn)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%w (%d pending)", ErrSchemaNotMigrated, len(pending))
+	}
+
+	return nil
+}
+
+func appliedVersions(conn *gorm.DB) (map[int]bool, error) {
+	err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamp NOT NULL
+	)`).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err := conn.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := map[int]bool{}
+	for _, row := range rows {
+		applied[row.Version] = true
+	}
+
+	return applied, nil
+}
+
+func execScript(tx *gorm.DB, script string) error {
+	for _, statement := range strings.Split(script, ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
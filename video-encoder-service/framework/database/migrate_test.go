@@ -0,0 +1,129 @@
+package database_test
+
+import (
+	"encoder/application/repository"
+	"encoder/domain"
+	"encoder/framework/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newEmptyDb(t *testing.T) *gorm.DB {
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.Nil(t, err)
+
+	return conn
+}
+
+// baselineVideo and baselineJob are the models AutoMigrate created tables
+// from before versioned migrations.
+type baselineVideo struct {
+	ID         string         `gorm:"type:uuid;primary_key"`
+	ResourceId string         `gorm:"type:uuid;notnull"`
+	FilePath   string         `gorm:"notnull"`
+	CreatedAt  time.Time      `gorm:"notnull"`
+	Jobs       []*baselineJob `gorm:"ForeignKey:VideoId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+func (baselineVideo) TableName() string {
+	return "videos"
+}
+
+type baselineJob struct {
+	ID               string `gorm:"type:uuid;primary_key"`
+	OutputBucketPath string
+	Status           string
+	VideoId          string `gorm:"column:video_id;type:uuid;notnull"`
+	Error            string
+	CreatedAt        time.Time
+	UpdateAt         time.Time
+}
+
+func (baselineJob) TableName() string {
+	return "jobs"
+}
+
+func TestLoadMigrations(t *testing.T) {
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		migrations, err := database.LoadMigrations(dialect)
+		require.Nil(t, err)
+
+		require.NotEmpty(t, migrations)
+		for i, migration := range migrations {
+			assert.Equal(t, i+1, migration.Version)
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+		}
+	}
+}
+
+func TestMigrateUp(t *testing.T) {
+	conn := newEmptyDb(t)
+
+	assert.ErrorIs(t, database.CheckSchema(conn), database.ErrSchemaNotMigrated)
+
+	applied, err := database.MigrateUp(conn)
+	require.Nil(t, err)
+	assert.Greater(t, applied, 0)
+
+	assert.Nil(t, database.CheckSchema(conn))
+	assert.True(t, conn.Migrator().HasColumn("jobs", "updated_at"))
+	assert.False(t, conn.Migrator().HasColumn("jobs", "update_at"))
+
+	applied, err = database.MigrateUp(conn)
+	require.Nil(t, err)
+	assert.Equal(t, 0, applied)
+}
+
+func TestMigrateDown(t *testing.T) {
+	conn := newEmptyDb(t)
+
+	_, err := database.MigrateUp(conn)
+	require.Nil(t, err)
+
+	migrations, err := database.LoadMigrations("sqlite")
+	require.Nil(t, err)
+
+	require.Nil(t, database.MigrateDown(conn, len(migrations)-1))
+	assert.ErrorIs(t, database.CheckSchema(conn), database.ErrSchemaNotMigrated)
+	assert.True(t, conn.Migrator().HasColumn("jobs", "update_at"))
+
+	require.Nil(t, database.MigrateDown(conn, 1))
+	assert.False(t, conn.Migrator().HasTable("jobs"))
+	assert.False(t, conn.Migrator().HasTable("videos"))
+
+	_, err = database.MigrateUp(conn)
+	assert.Nil(t, err)
+}
+
+func TestMigrateUp_FromAutoMigrateSchema(t *testing.T) {
+	conn := newEmptyDb(t)
+	require.Nil(t, conn.AutoMigrate(&baselineVideo{}, &baselineJob{}))
+
+	_, err := database.MigrateUp(conn)
+	require.Nil(t, err)
+	assert.Nil(t, database.CheckSchema(conn))
+
+	for _, column := range []string{"source_width", "thumbnails", "sprite_track", "clips", "integrated_loudness"} {
+		assert.True(t, conn.Migrator().HasColumn("videos", column), column)
+	}
+	for _, column := range []string{"options", "ladder", "parent_job_id", "chunk_index", "updated_at"} {
+		assert.True(t, conn.Migrator().HasColumn("jobs", column), column)
+	}
+
+	video := domain.NewVideo()
+	video.FilePath = "movie.mp4"
+	video.MediaInfo.Width = 1920
+	_, err = repository.NewVideoRepository(conn).Insert(video)
+	require.Nil(t, err)
+
+	job, err := domain.NewJob("bucket", "PENDING", video)
+	require.Nil(t, err)
+	_, err = repository.NewJobRepository(conn).Insert(job)
+	assert.Nil(t, err)
+}
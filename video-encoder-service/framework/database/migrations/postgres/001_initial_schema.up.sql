@@ -0,0 +1,20 @@
+-- Schema as created by AutoMigrate before versioned migrations, so existing
+-- databases can adopt them and get every later change from the following
+-- migrations.
+CREATE TABLE IF NOT EXISTS videos (
+    id uuid PRIMARY KEY,
+    resource_id uuid NOT NULL,
+    file_path text NOT NULL,
+    created_at timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id uuid PRIMARY KEY,
+    output_bucket_path text,
+    status text,
+    video_id uuid NOT NULL,
+    error text,
+    created_at timestamptz,
+    update_at timestamptz,
+    CONSTRAINT fk_videos_jobs FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
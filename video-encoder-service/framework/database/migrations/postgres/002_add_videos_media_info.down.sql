@@ -0,0 +1,10 @@
+ALTER TABLE videos DROP COLUMN source_audio_languages;
+ALTER TABLE videos DROP COLUMN source_audio_tracks;
+ALTER TABLE videos DROP COLUMN source_bitrate;
+ALTER TABLE videos DROP COLUMN source_frame_rate;
+ALTER TABLE videos DROP COLUMN source_audio_codec;
+ALTER TABLE videos DROP COLUMN source_video_codec;
+ALTER TABLE videos DROP COLUMN source_height;
+ALTER TABLE videos DROP COLUMN source_width;
+ALTER TABLE videos DROP COLUMN source_duration;
+ALTER TABLE videos DROP COLUMN source_container;
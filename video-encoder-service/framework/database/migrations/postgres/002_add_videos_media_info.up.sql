@@ -0,0 +1,10 @@
+ALTER TABLE videos ADD COLUMN source_container text;
+ALTER TABLE videos ADD COLUMN source_duration decimal;
+ALTER TABLE videos ADD COLUMN source_width bigint;
+ALTER TABLE videos ADD COLUMN source_height bigint;
+ALTER TABLE videos ADD COLUMN source_video_codec text;
+ALTER TABLE videos ADD COLUMN source_audio_codec text;
+ALTER TABLE videos ADD COLUMN source_frame_rate decimal;
+ALTER TABLE videos ADD COLUMN source_bitrate bigint;
+ALTER TABLE videos ADD COLUMN source_audio_tracks bigint;
+ALTER TABLE videos ADD COLUMN source_audio_languages text;
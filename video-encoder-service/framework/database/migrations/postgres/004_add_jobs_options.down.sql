@@ -0,0 +1 @@
+ALTER TABLE jobs DROP COLUMN options;
@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN integrated_loudness decimal;
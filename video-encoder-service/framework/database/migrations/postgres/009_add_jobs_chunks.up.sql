@@ -0,0 +1,4 @@
+ALTER TABLE jobs ADD COLUMN parent_job_id uuid;
+ALTER TABLE jobs ADD COLUMN chunk_index bigint;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_parent_job_id ON jobs (parent_job_id);
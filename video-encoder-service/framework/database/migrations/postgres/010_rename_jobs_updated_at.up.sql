@@ -0,0 +1 @@
+ALTER TABLE jobs RENAME COLUMN update_at TO updated_at;
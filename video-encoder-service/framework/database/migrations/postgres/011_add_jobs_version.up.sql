@@ -0,0 +1 @@
+ALTER TABLE jobs ADD COLUMN version bigint NOT NULL DEFAULT 1;
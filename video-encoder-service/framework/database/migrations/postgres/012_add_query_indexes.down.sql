@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_videos_created_at;
+DROP INDEX IF EXISTS idx_videos_resource_id;
+DROP INDEX IF EXISTS idx_jobs_updated_at;
+DROP INDEX IF EXISTS idx_jobs_created_at;
+DROP INDEX IF EXISTS idx_jobs_status;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_jobs_heartbeat_at;
+ALTER TABLE jobs DROP COLUMN heartbeat_at;
+ALTER TABLE jobs DROP COLUMN worker_id;
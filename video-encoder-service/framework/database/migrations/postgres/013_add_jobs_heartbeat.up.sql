@@ -0,0 +1,3 @@
+ALTER TABLE jobs ADD COLUMN worker_id text;
+ALTER TABLE jobs ADD COLUMN heartbeat_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_jobs_heartbeat_at ON jobs (heartbeat_at);
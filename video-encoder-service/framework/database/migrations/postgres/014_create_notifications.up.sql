@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id uuid PRIMARY KEY,
+    exchange text,
+    routing_key text,
+    content_type text,
+    body text NOT NULL,
+    created_at timestamptz,
+    published_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_published_at ON notifications (published_at, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_jobs_tenant;
+DROP INDEX IF EXISTS idx_jobs_pending;
+ALTER TABLE jobs DROP COLUMN tenant;
+ALTER TABLE jobs DROP COLUMN priority;
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs (status, created_at) WHERE parent_job_id IS NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE jobs ADD COLUMN priority bigint NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN tenant text NOT NULL DEFAULT '';
+DROP INDEX IF EXISTS idx_jobs_pending;
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs (status, priority DESC, created_at) WHERE parent_job_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant ON jobs (tenant, status);
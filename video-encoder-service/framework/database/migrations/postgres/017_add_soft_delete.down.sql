@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_jobs_deleted_at;
+DROP INDEX IF EXISTS idx_videos_deleted_at;
+ALTER TABLE jobs DROP COLUMN deleted_at;
+ALTER TABLE videos DROP COLUMN deleted_at;
@@ -0,0 +1,4 @@
+ALTER TABLE videos ADD COLUMN deleted_at timestamptz;
+ALTER TABLE jobs ADD COLUMN deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_videos_deleted_at ON videos (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_deleted_at ON jobs (deleted_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS renditions;
+ALTER TABLE videos DROP COLUMN hls_manifest;
+ALTER TABLE videos DROP COLUMN dash_manifest;
@@ -0,0 +1,20 @@
+-- Schema as created by AutoMigrate before versioned migrations, so existing
+-- databases can adopt them and get every later change from the following
+-- migrations.
+CREATE TABLE IF NOT EXISTS videos (
+    id text PRIMARY KEY,
+    resource_id text NOT NULL,
+    file_path text NOT NULL,
+    created_at datetime NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id text PRIMARY KEY,
+    output_bucket_path text,
+    status text,
+    video_id text NOT NULL,
+    error text,
+    created_at datetime,
+    update_at datetime,
+    CONSTRAINT fk_videos_jobs FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,10 @@
+ALTER TABLE videos ADD COLUMN source_container text;
+ALTER TABLE videos ADD COLUMN source_duration real;
+ALTER TABLE videos ADD COLUMN source_width integer;
+ALTER TABLE videos ADD COLUMN source_height integer;
+ALTER TABLE videos ADD COLUMN source_video_codec text;
+ALTER TABLE videos ADD COLUMN source_audio_codec text;
+ALTER TABLE videos ADD COLUMN source_frame_rate real;
+ALTER TABLE videos ADD COLUMN source_bitrate integer;
+ALTER TABLE videos ADD COLUMN source_audio_tracks integer;
+ALTER TABLE videos ADD COLUMN source_audio_languages text;
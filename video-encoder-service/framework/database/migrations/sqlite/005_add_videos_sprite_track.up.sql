@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN sprite_track text;
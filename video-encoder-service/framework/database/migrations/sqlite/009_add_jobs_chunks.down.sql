@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_jobs_parent_job_id;
+ALTER TABLE jobs DROP COLUMN chunk_index;
+ALTER TABLE jobs DROP COLUMN parent_job_id;
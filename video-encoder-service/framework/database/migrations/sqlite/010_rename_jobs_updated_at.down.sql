@@ -0,0 +1 @@
+ALTER TABLE jobs RENAME COLUMN updated_at TO update_at;
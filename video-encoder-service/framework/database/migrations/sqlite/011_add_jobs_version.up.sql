@@ -0,0 +1 @@
+ALTER TABLE jobs ADD COLUMN version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs (status);
+CREATE INDEX IF NOT EXISTS idx_jobs_created_at ON jobs (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_jobs_updated_at ON jobs (updated_at);
+CREATE INDEX IF NOT EXISTS idx_videos_resource_id ON videos (resource_id);
+CREATE INDEX IF NOT EXISTS idx_videos_created_at ON videos (created_at, id);
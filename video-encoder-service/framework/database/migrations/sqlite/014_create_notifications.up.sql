@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id text PRIMARY KEY,
+    exchange text,
+    routing_key text,
+    content_type text,
+    body text NOT NULL,
+    created_at datetime,
+    published_at datetime
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_published_at ON notifications (published_at, created_at);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_jobs_pending ON jobs (status, created_at) WHERE parent_job_id IS NULL;
@@ -0,0 +1,20 @@
+ALTER TABLE videos ADD COLUMN dash_manifest text;
+ALTER TABLE videos ADD COLUMN hls_manifest text;
+
+CREATE TABLE IF NOT EXISTS renditions (
+    id text PRIMARY KEY,
+    video_id text NOT NULL,
+    type text,
+    codec text,
+    width integer,
+    height integer,
+    bitrate integer,
+    language text,
+    segment_count integer,
+    total_bytes integer,
+    path text,
+    created_at datetime,
+    CONSTRAINT fk_videos_renditions FOREIGN KEY (video_id) REFERENCES videos (id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_renditions_video_id ON renditions (video_id);
@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN trimmed_duration real;
@@ -0,0 +1,140 @@
+package drm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+)
+
+var hexKey = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// ContentKey is a CENC key pair as hex strings, e.g. the KID and key passed
+// to mp4dash --encryption-key.
+type ContentKey struct {
+	KeyID string `json:"kid"`
+	Key   string `json:"key"`
+}
+
+func (k ContentKey) Validate() error {
+	if !hexKey.MatchString(k.KeyID) {
+		return fmt.Errorf("invalid key id: expected 32 hex characters")
+	}
+	if !hexKey.MatchString(k.Key) {
+		return fmt.Errorf("invalid key: expected 32 hex characters")
+	}
+
+	return nil
+}
+
+type KeyProvider interface {
+	GetKey(resourceID string) (*ContentKey, error)
+}
+
+// NewKeyProvider builds the provider selected by DRM_KEY_PROVIDER ("static"
+// or "http"). It returns nil when encryption is not configured.
+func NewKeyProvider() (KeyProvider, error) {
+	switch os.Getenv("DRM_KEY_PROVIDER") {
+	case "":
+		return nil, nil
+	case "static":
+		return NewStaticFileKeyProvider(os.Getenv("DRM_KEY_FILE")), nil
+	case "http":
+		return NewHTTPKeyProvider(os.Getenv("DRM_KEY_SERVER_URL"), os.Getenv("DRM_KEY_SERVER_TOKEN")), nil
+	}
+
+	return nil, fmt.Errorf("unknown DRM_KEY_PROVIDER %q", os.Getenv("DRM_KEY_PROVIDER"))
+}
+
+// StaticFileKeyProvider reads keys from a JSON file mapping resource ids to
+// keys. The "default" entry is used for resources without their own key.
+// Meant for development only.
+type StaticFileKeyProvider struct {
+	Path string
+}
+
+func NewStaticFileKeyProvider(path string) *StaticFileKeyProvider {
+	return &StaticFileKeyProvider{Path: path}
+}
+
+func (p *StaticFileKeyProvider) GetKey(resourceID string) (*ContentKey, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys map[string]ContentKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid key file %v: %w", p.Path, err)
+	}
+
+	key, ok := keys[resourceID]
+	if !ok {
+		key, ok = keys["default"]
+	}
+	if !ok {
+		return nil, fmt.Errorf("no key for resource %v", resourceID)
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// HTTPKeyProvider asks a key server for the key of a resource by posting
+// {"resource_id": "..."} and reading a {"kid": "...", "key": "..."} reply.
+type HTTPKeyProvider struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func NewHTTPKeyProvider(url string, token string) *HTTPKeyProvider {
+	return &HTTPKeyProvider{
+		URL:    url,
+		Token:  token,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *HTTPKeyProvider) GetKey(resourceID string) (*ContentKey, error) {
+	body, err := json.Marshal(map[string]string{"resource_id": resourceID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	res, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("key server responded with status %d", res.StatusCode)
+	}
+
+	var key ContentKey
+	if err := json.NewDecoder(res.Body).Decode(&key); err != nil {
+		return nil, fmt.Errorf("invalid key server response: %w", err)
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
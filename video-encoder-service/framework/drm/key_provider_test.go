@@ -0,0 +1,69 @@
+package drm_test
+
+import (
+	"encoder/framework/drm"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKeyID = "0123456789abcdef0123456789abcdef"
+	testKey   = "fedcba9876543210fedcba9876543210"
+)
+
+func TestStaticFileKeyProvider_GetKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	keys := `{"default": {"kid": "` + testKeyID + `", "key": "` + testKey + `"}}`
+	require.Nil(t, os.WriteFile(path, []byte(keys), 0644))
+
+	key, err := drm.NewStaticFileKeyProvider(path).GetKey("resource")
+
+	require.Nil(t, err)
+	assert.Equal(t, testKeyID, key.KeyID)
+	assert.Equal(t, testKey, key.Key)
+}
+
+func TestStaticFileKeyProvider_MissingKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.Nil(t, os.WriteFile(path, []byte(`{}`), 0644))
+
+	_, err := drm.NewStaticFileKeyProvider(path).GetKey("resource")
+
+	assert.NotNil(t, err)
+}
+
+func TestHTTPKeyProvider_GetKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "resource", body["resource_id"])
+
+		json.NewEncoder(w).Encode(drm.ContentKey{KeyID: testKeyID, Key: testKey})
+	}))
+	defer server.Close()
+
+	key, err := drm.NewHTTPKeyProvider(server.URL, "secret").GetKey("resource")
+
+	require.Nil(t, err)
+	assert.Equal(t, testKeyID, key.KeyID)
+}
+
+func TestHTTPKeyProvider_InvalidKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(drm.ContentKey{KeyID: "abc", Key: testKey})
+	}))
+	defer server.Close()
+
+	_, err := drm.NewHTTPKeyProvider(server.URL, "").GetKey("resource")
+
+	assert.NotNil(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/streadway/amqp"
 )
@@ -16,6 +17,9 @@ type RabbitMQ struct {
 	Vhost             string
 	ConsumerQueueName string
 	ConsumerName      string
+	DelayQueueName    string
+	DelaySeconds      int
+	PrefetchCount     int
 	AutoAck           bool
 	Args              amqp.Table
 	Channel           *amqp.Channel
@@ -25,6 +29,25 @@ func NewRabbitMQ() *RabbitMQ {
 	rabbitMQArgs := amqp.Table{}
 	rabbitMQArgs["x-dead-letter-exchange"] = os.Getenv("RABBITMQ_DLX")
 
+	// Priorities only apply to queues declared with x-max-priority, an
+	// existing queue has to be deleted and declared again to get one.
+	if maxPriority, err := strconv.Atoi(os.Getenv("RABBITMQ_MAX_PRIORITY")); err == nil && maxPriority > 0 {
+		rabbitMQArgs["x-max-priority"] = maxPriority
+	}
+
+	// Without a prefetch limit the broker pushes the whole queue at once and
+	// priorities have no effect.
+	prefetchCount, _ := strconv.Atoi(os.Getenv("RABBITMQ_PREFETCH_COUNT"))
+
+	delayQueueName := os.Getenv("RABBITMQ_DELAY_QUEUE_NAME")
+	if delayQueueName == "" {
+		delayQueueName = os.Getenv("RABBITMQ_CONSUMER_QUEUE_NAME") + ".delay"
+	}
+	delaySeconds, err := strconv.Atoi(os.Getenv("RABBITMQ_DELAY_SECONDS"))
+	if err != nil || delaySeconds <= 0 {
+		delaySeconds = 30
+	}
+
 	rabbitMQ := RabbitMQ{
 		User:              os.Getenv("RABBITMQ_DEFAULT_USER"),
 		Password:          os.Getenv("RABBITMQ_DEFAULT_PASS"),
@@ -33,6 +56,9 @@ func NewRabbitMQ() *RabbitMQ {
 		Vhost:             os.Getenv("RABBITMQ_DEFAULT_VHOST"),
 		ConsumerQueueName: os.Getenv("RABBITMQ_CONSUMER_QUEUE_NAME"),
 		ConsumerName:      os.Getenv("RABBITMQ_CONSUMER_NAME"),
+		DelayQueueName:    delayQueueName,
+		DelaySeconds:      delaySeconds,
+		PrefetchCount:     prefetchCount,
 		AutoAck:           false,
 		Args:              rabbitMQArgs,
 	}
@@ -62,6 +88,27 @@ func (r *RabbitMQ) Consume(messageChannel chan amqp.Delivery) {
 	)
 	failOnError(err, "failed to declare a queue")
 
+	// Deferred messages wait in the delay queue until their TTL expires, then
+	// are dead-lettered back to the tail of the consumer queue.
+	_, err = r.Channel.QueueDeclare(
+		r.DelayQueueName,
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{
+			"x-message-ttl":             int32(r.DelaySeconds * 1000),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": r.ConsumerQueueName,
+		},
+	)
+	failOnError(err, "failed to declare the delay queue")
+
+	if r.PrefetchCount > 0 {
+		err = r.Channel.Qos(r.PrefetchCount, 0, false)
+		failOnError(err, "failed to set the prefetch count")
+	}
+
 	incomingMessage, err := r.Channel.Consume(
 		q.Name,
 		r.ConsumerName,
@@ -84,7 +131,10 @@ func (r *RabbitMQ) Consume(messageChannel chan amqp.Delivery) {
 }
 
 func (r *RabbitMQ) Notify(message string, contentType string, exchange string, routingKey string) error {
+	return r.Publish(message, contentType, exchange, routingKey, 0, nil)
+}
 
+func (r *RabbitMQ) Publish(message string, contentType string, exchange string, routingKey string, priority uint8, headers map[string]any) error {
 	err := r.Channel.Publish(
 		exchange,
 		routingKey,
@@ -92,6 +142,8 @@ func (r *RabbitMQ) Notify(message string, contentType string, exchange string, r
 		false,
 		amqp.Publishing{
 			ContentType: contentType,
+			Priority:    priority,
+			Headers:     headers,
 			Body:        []byte(message),
 		})
 	if err != nil {
@@ -101,6 +153,29 @@ func (r *RabbitMQ) Notify(message string, contentType string, exchange string, r
 	return nil
 }
 
+// Defer acknowledges the message after publishing a copy of it to the delay
+// queue, so it comes back to the consumer queue DelaySeconds later instead
+// of being redelivered right away like a requeued message.
+func (r *RabbitMQ) Defer(message amqp.Delivery) error {
+	err := r.Channel.Publish(
+		"",
+		r.DelayQueueName,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType: message.ContentType,
+			Priority:    message.Priority,
+			Headers:     message.Headers,
+			MessageId:   message.MessageId,
+			Body:        message.Body,
+		})
+	if err != nil {
+		return err
+	}
+
+	return message.Ack(false)
+}
+
 func failOnError(err error, msg string) {
 	if err != nil {
 		log.Fatalf("%s: %s", msg, err)
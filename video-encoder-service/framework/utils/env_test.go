@@ -0,0 +1,31 @@
+package utils_test
+
+import (
+	"encoder/framework/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvInt_Fallback(t *testing.T) {
+	t.Setenv("ENCODER_TEST_INT", "")
+
+	value, err := utils.EnvInt("ENCODER_TEST_INT", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, value)
+}
+
+func TestEnvInt_Value(t *testing.T) {
+	t.Setenv("ENCODER_TEST_INT", "7")
+
+	value, err := utils.EnvInt("ENCODER_TEST_INT", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, value)
+}
+
+func TestEnvInt_Invalid(t *testing.T) {
+	t.Setenv("ENCODER_TEST_INT", "abc")
+
+	_, err := utils.EnvInt("ENCODER_TEST_INT", 3)
+	assert.NotNil(t, err)
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strconv"
+)
 
 func IsJson(str string) error {
 	var js map[string]interface{}
@@ -11,3 +14,7 @@ func IsJson(str string) error {
 
 	return nil
 }
+
+func FormatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}